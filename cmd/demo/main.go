@@ -5,9 +5,11 @@ import (
 	"fmt"
 	"io/ioutil"
 	"log"
+	"os"
 	"time"
 
 	"github.com/flipb/qbis-time/pkg/qbis"
+	"github.com/flipb/qbis-time/pkg/qbis/api"
 )
 
 //Config holds QBis configuration
@@ -16,6 +18,10 @@ type Config struct {
 	Company  string
 	User     string
 	Password string
+	// SessionFile is where the session is kept between runs, leave empty to always log in
+	SessionFile string
+	// Location is the time zone of the Qbis user, eg. "Europe/Stockholm". Leave empty to use the local time zone
+	Location string
 }
 
 func main() {
@@ -30,11 +36,33 @@ func main() {
 		log.Fatalf("unable to parse config file: %v", err)
 	}
 
-	q, err := qbis.NewClient(config.Company, config.User, config.Password)
+	logger := log.New(os.Stderr, "qbis: ", log.LstdFlags)
+	opts := []qbis.Option{
+		qbis.WithLogger(logger),
+		qbis.WithSchemaDriftHandler(api.LogSchemaDrift(logger)),
+	}
+	if config.URL != "" {
+		opts = append(opts, qbis.WithBaseURL(config.URL))
+	}
+	if config.Location != "" {
+		loc, err := time.LoadLocation(config.Location)
+		if err != nil {
+			log.Fatalf("invalid location in config file: %v", err)
+		}
+		opts = append(opts, qbis.WithLocation(loc))
+	}
+	if config.SessionFile != "" {
+		opts = append(opts, qbis.WithSessionStore(api.NewFileSessionStore(config.SessionFile)))
+	}
+
+	q, err := qbis.NewClient(config.Company, config.User, config.Password, opts...)
 	if err != nil {
 		log.Fatal(err)
 	}
 
+	me := q.LoginInfo()
+	fmt.Printf("Logged in as %s (employee %s) at %s\n", me.UserName, me.EmployeeID, me.Company)
+
 	// Get the next week's timesheet
 	w, err := q.Week(time.Now().AddDate(0, 0, 7))
 	if err != nil {
@@ -52,7 +80,10 @@ func main() {
 		log.Fatalf("error setting arrival time: %v\n", err)
 	}
 
-	s := w.SalaryTimeActivities()
+	s, err := w.SalaryTimeActivities()
+	if err != nil {
+		log.Fatal(err)
+	}
 	for _, s := range s {
 		fmt.Printf("SalaryActivity ID %d %s - %v\n", s.ActivityID(), s.Name(), s.InWeek())
 	}
@@ -86,10 +117,12 @@ func main() {
 		fmt.Printf("ProjectTime Activity: %s, %dm : %s\n", pt.Name(), d.ProjectTimeMinutes(pt.ActivityID()), d.ProjectTimeInternalNotes(pt.ActivityID()))
 	}
 
-	err = w.Save()
+	fmt.Printf("Saving changes:\n%s\n", qbis.FormatDiff(w.Diff()))
+	result, err := w.Save()
 	if err != nil {
 		log.Fatalf("error saving week: %v\n", err)
 	}
+	printSaveResult(result)
 
 	pts = d.LoggedProjectTimeActivities()
 	for _, pt := range pts {
@@ -103,29 +136,71 @@ func main() {
 		log.Fatal(err)
 	}
 
-	err = w.Save()
+	// preview what will be sent before saving
+	preview, err := w.DryRun()
+	if err != nil {
+		log.Fatal(err)
+	}
+	payload, err := preview.JSON(qbis.SectionSalaryTime)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("Salary time payload: %s", payload)
+	if err := preview.Err(); err != nil {
+		log.Fatal(err)
+	}
+
+	result, err = w.Save()
 	if err != nil {
 		log.Fatal(err.Error())
 	}
+	printSaveResult(result)
 	salt = d.LoggedSalaryTimeActivities()
 	for _, s := range salt {
 		fmt.Printf("SalaryTime Activity: %s, %dm\n", s.Name(), d.SalaryTimeMinutes(s.ActivityID()))
 	}
 
 	// RESET WORKING TIME BELOW
-	err = d.SetArrival(d.Date.Add(time.Hour * 8))
+	err = d.SetWorkingHours(qbis.ClockTime(8*60), qbis.ClockTime(17*60))
 	if err != nil {
 		println(err.Error())
 	}
-	err = d.SetDeparture(d.Date.Add(time.Hour * 17))
+
+	result, err = w.Save()
 	if err != nil {
 		println(err.Error())
 	}
+	printSaveResult(result)
 
-	err = w.Save()
+	logged, err := d.LoggedMinutes()
 	if err != nil {
-		println(err.Error())
+		log.Fatal(err)
 	}
+	fmt.Printf("Time spent working = %d (want: 480)", logged)
+}
 
-	fmt.Printf("Time spent working = %d (want: 480)", d.LoggedMinutes())
+//printSaveResult prints what was saved and the warnings Qbis returned
+func printSaveResult(result *qbis.SaveResult) {
+	if result == nil {
+		return
+	}
+	for _, section := range result.Sections {
+		switch {
+		case section.RolledBack:
+			fmt.Printf("Rolled back %v\n", section.Section)
+		case section.Saved:
+			fmt.Printf("Saved %v\n", section.Section)
+		case section.Attempted:
+			fmt.Printf("Failed to save %v: %v\n", section.Section, section.Err)
+		}
+	}
+	for _, limit := range result.LimitErrors {
+		fmt.Printf("Limit error: %v\n", limit)
+	}
+	for _, limit := range result.LimitWarnings {
+		fmt.Printf("Limit warning: %v\n", limit)
+	}
+	for _, message := range result.Messages {
+		fmt.Printf("Warning: %v\n", message)
+	}
 }
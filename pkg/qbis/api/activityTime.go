@@ -12,19 +12,19 @@ type ActivityBase struct {
 	ActivityID     int    `json:"ActivityId"`
 	ActivityName   string `json:"ActivityName"`
 	Days           []struct {
-		DayID            int    `json:"DayId"`
-		DayDate          string `json:"DayDate"`
-		DayMinutes       int    `json:"DayMinutes"`
-		DayDays          int    `json:"DayDays"`
-		Notes            string `json:"Notes"`
-		Delete           bool   `json:"Delete"`
-		Locked           bool   `json:"Locked"`
-		IsReadOnly       bool   `json:"IsReadOnly"`
-		IsPrefilled      bool   `json:"IsPrefilled"`
-		AllowEmptyFromTo bool   `json:"AllowEmptyFromTo"`
-		LunchOffset      int    `json:"LunchOffset"`
-		DayFromMinutes   int    `json:"DayFromMinutes"`
-		DayToMinutes     int    `json:"DayToMinutes"`
+		DayID            int     `json:"DayId"`
+		DayDate          Date    `json:"DayDate"`
+		DayMinutes       Minutes `json:"DayMinutes"`
+		DayDays          int     `json:"DayDays"`
+		Notes            string  `json:"Notes"`
+		Delete           bool    `json:"Delete"`
+		Locked           bool    `json:"Locked"`
+		IsReadOnly       bool    `json:"IsReadOnly"`
+		IsPrefilled      bool    `json:"IsPrefilled"`
+		AllowEmptyFromTo bool    `json:"AllowEmptyFromTo"`
+		LunchOffset      int     `json:"LunchOffset"`
+		DayFromMinutes   Minutes `json:"DayFromMinutes"`
+		DayToMinutes     Minutes `json:"DayToMinutes"`
 	} `json:"Days"`
 	EmployeeID int      `json:"EmployeeId"`
 	Factor     float64  `json:"Factor"`
@@ -33,6 +33,24 @@ type ActivityBase struct {
 	Tooltip    []string `json:"Tooltip"`
 }
 
+//LimitResult is a limit error or warning returned when saving salary or project time,
+//eg. when more time is registered on an activity than its limit allows
+type LimitResult struct {
+	ActivityID   int     `json:"ActivityId"`
+	ActivityName string  `json:"ActivityName"`
+	DayDate      Date    `json:"DayDate"`
+	Limit        Minutes `json:"Limit"`
+	Value        Minutes `json:"Value"`
+	Message      string  `json:"Message"`
+}
+
+func (r LimitResult) String() string {
+	if r.Message != "" {
+		return fmt.Sprintf("%s (%d): %s", r.ActivityName, r.ActivityID, r.Message)
+	}
+	return fmt.Sprintf("%s (%d): %d minutes exceeds the limit of %d minutes", r.ActivityName, r.ActivityID, r.Value, r.Limit)
+}
+
 /*ActivityOverviewBase see full comment for details
 // Example of Properties
 [
@@ -0,0 +1,101 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+//AuthErrorKind describes why Qbis did not accept a login attempt
+type AuthErrorKind int
+
+const (
+	//AuthUnexpectedPage means the login ended up on a page we do not recognize
+	AuthUnexpectedPage AuthErrorKind = iota
+	//AuthInvalidCredentials means the user name or password was rejected
+	AuthInvalidCredentials
+	//AuthUnknownCompany means Qbis does not know the company
+	AuthUnknownCompany
+	//AuthLockedAccount means the user account is locked
+	AuthLockedAccount
+)
+
+func (k AuthErrorKind) String() string {
+	switch k {
+	case AuthInvalidCredentials:
+		return "invalid credentials"
+	case AuthUnknownCompany:
+		return "unknown company"
+	case AuthLockedAccount:
+		return "account locked"
+	default:
+		return "unexpected page"
+	}
+}
+
+//AuthError is returned by Login when Qbis did not accept the login.
+//Use errors.Is with ErrInvalidCredentials, ErrUnknownCompany, ErrLockedAccount or ErrUnexpectedPage
+//to branch on the reason, or errors.As to get at the details.
+type AuthError struct {
+	Kind AuthErrorKind
+	//Path is the path of the page the login ended up on
+	Path string
+	//Message is the error message shown on the login page, if any was found
+	Message string
+}
+
+//Sentinel values to compare AuthErrors against with errors.Is
+var (
+	ErrInvalidCredentials = &AuthError{Kind: AuthInvalidCredentials}
+	ErrUnknownCompany     = &AuthError{Kind: AuthUnknownCompany}
+	ErrLockedAccount      = &AuthError{Kind: AuthLockedAccount}
+	ErrUnexpectedPage     = &AuthError{Kind: AuthUnexpectedPage}
+)
+
+func (e *AuthError) Error() string {
+	msg := "login failed: " + e.Kind.String()
+	if e.Message != "" {
+		msg += fmt.Sprintf(" (%s)", e.Message)
+	}
+	if e.Path != "" {
+		msg += fmt.Sprintf(" [ended up on %s]", e.Path)
+	}
+	return msg
+}
+
+//Is reports whether target is an AuthError of the same kind
+func (e *AuthError) Is(target error) bool {
+	t, ok := target.(*AuthError)
+	if !ok {
+		return false
+	}
+	return t.Kind == e.Kind
+}
+
+//isLoginPath returns true if the path belongs to the login pages
+func isLoginPath(path string) bool {
+	return strings.HasPrefix(strings.ToLower(path), "/login")
+}
+
+//redirectChain returns the paths visited to get the response, in order
+func redirectChain(response *http.Response) []string {
+	paths := make([]string, 0)
+	for r := response; r != nil && r.Request != nil; r = r.Request.Response {
+		paths = append([]string{r.Request.URL.Path}, paths...)
+	}
+	return paths
+}
+
+//classifyLoginMessage guesses the reason for a failed login from the message on the login page.
+//Qbis shows the messages in the users language, so we look for both english and swedish words.
+func classifyLoginMessage(message string) AuthErrorKind {
+	m := strings.ToLower(message)
+	switch {
+	case strings.Contains(m, "lock"), strings.Contains(m, "låst"), strings.Contains(m, "spärr"):
+		return AuthLockedAccount
+	case strings.Contains(m, "company"), strings.Contains(m, "företag"):
+		return AuthUnknownCompany
+	default:
+		return AuthInvalidCredentials
+	}
+}
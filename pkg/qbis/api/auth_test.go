@@ -0,0 +1,24 @@
+package api
+
+import "testing"
+
+func TestClassifyLoginMessage(t *testing.T) {
+	tests := []struct {
+		message string
+		want    AuthErrorKind
+	}{
+		{"Invalid user name or password", AuthInvalidCredentials},
+		{"Felaktigt användarnamn eller lösenord", AuthInvalidCredentials},
+		{"", AuthInvalidCredentials},
+		{"Unknown company", AuthUnknownCompany},
+		{"Okänt företag", AuthUnknownCompany},
+		{"Your account is LOCKED", AuthLockedAccount},
+		{"Ditt konto är låst", AuthLockedAccount},
+		{"Kontot är spärrat", AuthLockedAccount},
+	}
+	for _, test := range tests {
+		if got := classifyLoginMessage(test.message); got != test.want {
+			t.Errorf("classifyLoginMessage(%q) = %v, want %v", test.message, got, test.want)
+		}
+	}
+}
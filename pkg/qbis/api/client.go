@@ -1,22 +1,67 @@
 package api
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net/http"
 	"net/http/cookiejar"
 	"net/url"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
-// Client implements a Qbis client
+//DefaultLanguage is the language used when logging in unless another one is set with WithLanguage
+const DefaultLanguage = "lang_english"
+
+//Logger is used by the client to log what it is doing. *log.Logger implements it
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+//nopLogger discards everything
+type nopLogger struct{}
+
+func (nopLogger) Printf(format string, v ...interface{}) {}
+
+//ErrSessionExpired is returned when the session has expired and the client is unable to log in again,
+//eg. because it was restored from a stored session and has no credentials
+var ErrSessionExpired = errors.New("qbis session has expired")
+
+// Client implements a Qbis client.
+// Every client has its own cookie jar and transport, so a process can hold many sessions at once.
+// A Client is safe for concurrent use by multiple goroutines.
 type Client struct {
-	client *http.Client
-	url    string
+	client   *http.Client
+	url      string
+	language string
+	logger   Logger
+	// driftHandler is told about responses that do not match our types, if set
+	driftHandler SchemaDriftHandler
+	// reloginHandler is called after logging in again because the session expired, if set
+	reloginHandler func(c *Client)
+
+	// loginMu makes sure only one goroutine logs in again when the session expires
+	loginMu sync.Mutex
+
+	// mu guards the fields below
+	mu sync.Mutex
+	// credentials are kept after a successful login so we can log in again when the session expires
+	credentials *credentials
+	// logins is incremented on every successful login
+	logins uint64
+}
+
+type credentials struct {
+	company  string
+	user     string
+	password string
 }
 
 type authFormdata struct {
@@ -35,12 +80,16 @@ type cookieData struct {
 // New constructs a new Client
 func New(url string) *Client {
 	client := new(Client)
-	client.client = http.DefaultClient
+	client.client = &http.Client{
+		Transport: newTransport(),
+	}
 
 	if !strings.HasSuffix(url, "/") {
 		url = url + "/"
 	}
 	client.url = url
+	client.language = DefaultLanguage
+	client.logger = nopLogger{}
 
 	// after url is set
 	client.loadCookies()
@@ -48,14 +97,50 @@ func New(url string) *Client {
 	return client
 }
 
-// WithHTTPClient sets the http.Client to be used by the qbis client
+//newTransport returns a transport of our own, so that clients do not share connections.
+//If http.DefaultTransport has been replaced, eg. by a mocking library, it is used as is.
+func newTransport() http.RoundTripper {
+	transport, ok := http.DefaultTransport.(*http.Transport)
+	if !ok {
+		return http.DefaultTransport
+	}
+	return transport.Clone()
+}
+
+// WithHTTPClient sets the http.Client to be used by the qbis client.
+// The client is copied, if it has no cookie jar the copy gets its own.
 func (c *Client) WithHTTPClient(client *http.Client) *Client {
-	c.client = client
+	httpClient := *client
+	c.client = &httpClient
+	c.loadCookies()
 	return c
 }
 
-//loadCookies requires Client.url to be set
-func (c Client) loadCookies() error {
+// WithLanguage sets the language sent when logging in, eg. "lang_english".
+// It decides the language of the messages Qbis returns
+func (c *Client) WithLanguage(language string) *Client {
+	c.language = language
+	return c
+}
+
+// WithLogger sets a logger that gets told what the client is doing, eg. when it logs in again
+func (c *Client) WithLogger(logger Logger) *Client {
+	if logger == nil {
+		logger = nopLogger{}
+	}
+	c.logger = logger
+	return c
+}
+
+// WithReloginHandler sets a function that is called after the client has logged in again because the session expired,
+// eg. to store the new session
+func (c *Client) WithReloginHandler(handler func(c *Client)) *Client {
+	c.reloginHandler = handler
+	return c
+}
+
+//loadCookies makes sure the http client has a cookie jar
+func (c *Client) loadCookies() error {
 	var err error
 	if c.client.Jar == nil {
 		c.client.Jar, err = cookiejar.New(&cookiejar.Options{})
@@ -63,46 +148,24 @@ func (c Client) loadCookies() error {
 			return err
 		}
 	}
-	_, err = c.get("/Login/Login")
-	if err != nil {
-		return err
-	}
 	return nil
 }
 
 //GetUserEmployeeID returns the string representation of the logged in users employee id
-func (c Client) GetUserEmployeeID() (string, error) {
-	res, err := c.get("/Time/TimeOverview")
-	if err != nil {
-		return "", err
-	}
+func (c *Client) GetUserEmployeeID() (string, error) {
+	return c.GetUserEmployeeIDContext(context.Background())
+}
 
-	scripts, err := getEmbeddedScriptsInHTML(res.Body)
+//GetUserEmployeeIDContext is like GetUserEmployeeID but with a context
+func (c *Client) GetUserEmployeeIDContext(ctx context.Context) (string, error) {
+	info, err := c.GetLoginInfoContext(ctx)
 	if err != nil {
 		return "", err
 	}
-	if len(scripts) == 0 {
-		return "", fmt.Errorf("error loading scripts from TimeOverview page")
-	}
-
-	employeeID := ""
-	for _, script := range scripts {
-		str, err := getCurrentUserFromCurrentLoginBlock(script)
-		if err != nil {
-			//println(err.Error())
-			continue
-		}
-
-		employeeID = str
-	}
-	if employeeID == "" {
-		return "", fmt.Errorf("unable to find EmployeeID in scripts")
-	}
-
-	return employeeID, nil
+	return info.EmployeeID, nil
 }
 
-func (c Client) printCookies() error {
+func (c *Client) printCookies() error {
 	u, err := url.Parse(c.url)
 	if err != nil {
 		return err
@@ -112,57 +175,182 @@ func (c Client) printCookies() error {
 	return nil
 }
 
-func (c Client) get(resource string) (*http.Response, error) {
+func (c *Client) resourceURL(resource string) string {
 	if strings.HasPrefix(resource, "/") {
 		resource = strings.TrimLeft(resource, "/")
 	}
+	return c.url + resource
+}
+
+//get requests a resource that responds with json
+func (c *Client) get(ctx context.Context, resource string) (*http.Response, error) {
+	return c.do(ctx, http.MethodGet, resource, "", nil, true)
+}
+
+//getPage requests a resource that responds with html
+func (c *Client) getPage(ctx context.Context, resource string) (*http.Response, error) {
+	return c.do(ctx, http.MethodGet, resource, "", nil, false)
+}
+
+//postData posts a form. It is only used to log in and is never retried
+func (c *Client) postData(ctx context.Context, resource string, data url.Values) (*http.Response, error) {
+	return c.send(ctx, http.MethodPost, resource, "application/x-www-form-urlencoded", []byte(data.Encode()))
+}
+
+//EncodePayload returns the json body that is posted to qbis when saving the payload
+func EncodePayload(payload interface{}) ([]byte, error) {
+	var b bytes.Buffer
+	err := json.NewEncoder(&b).Encode(payload)
+	if err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
 
-	resp, err := c.client.Get(c.url + resource)
+func (c *Client) postJSON(ctx context.Context, resource string, data io.Reader) (*http.Response, error) {
+	// we keep the payload around in case we have to log in again and replay the request
+	body, err := ioutil.ReadAll(data)
 	if err != nil {
 		return nil, err
 	}
-	return resp, nil
+	return c.do(ctx, http.MethodPost, resource, "application/json", body, true)
 }
 
-func (c Client) postData(resource string, data url.Values) (*http.Response, error) {
-	if strings.HasPrefix(resource, "/") {
-		resource = strings.TrimLeft(resource, "/")
+//do sends the request. If the session has expired it logs in again and replays the request once
+func (c *Client) do(ctx context.Context, method string, resource string, contentType string, body []byte, expectJSON bool) (*http.Response, error) {
+	logins := c.loginCount()
+	resp, err := c.send(ctx, method, resource, contentType, body)
+	if err != nil {
+		return nil, err
 	}
+	if !sessionExpired(resp, expectJSON) {
+		return checked(resp)
+	}
+	resp.Body.Close()
+
+	err = c.relogin(ctx, logins)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err = c.send(ctx, method, resource, contentType, body)
+	if err != nil {
+		return nil, err
+	}
+	if isLoginPath(resp.Request.URL.Path) {
+		resp.Body.Close()
+		return nil, ErrSessionExpired
+	}
+	return checked(resp)
+}
 
-	resp, err := c.client.PostForm(c.url+resource, data)
+//checked returns the response if it has a 2xx status code and an APIError otherwise
+func checked(resp *http.Response) (*http.Response, error) {
+	err := checkResponse(resp)
 	if err != nil {
 		return nil, err
 	}
 	return resp, nil
 }
 
-func (c Client) postJSON(resource string, data io.Reader) (*http.Response, error) {
-	if strings.HasPrefix(resource, "/") {
-		resource = strings.TrimLeft(resource, "/")
+//send sends a single request
+func (c *Client) send(ctx context.Context, method string, resource string, contentType string, body []byte) (*http.Response, error) {
+	var data io.Reader
+	if body != nil {
+		data = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.resourceURL(resource), data)
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
 	}
 
-	resp, err := c.client.Post(c.url+resource, "application/json", data)
+	resp, err := c.client.Do(req)
 	if err != nil {
 		return nil, err
 	}
 	return resp, nil
 }
 
+//loginCount returns the number of successful logins
+func (c *Client) loginCount() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.logins
+}
+
+//loggedIn stores the credentials of a successful login
+func (c *Client) loggedIn(creds *credentials) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.credentials = creds
+	c.logins++
+}
+
+//relogin logs in again with the credentials from the last successful login.
+//logins is the login count seen before the request that found the session expired,
+//if another goroutine has logged in since then we don't have to do it again.
+func (c *Client) relogin(ctx context.Context, logins uint64) error {
+	c.loginMu.Lock()
+	defer c.loginMu.Unlock()
+
+	c.mu.Lock()
+	creds := c.credentials
+	current := c.logins
+	c.mu.Unlock()
+
+	if current != logins {
+		return nil
+	}
+	if creds == nil {
+		return ErrSessionExpired
+	}
+	c.logger.Printf("qbis session has expired, logging in again as %s", creds.user)
+	err := c.LoginContext(ctx, creds.company, creds.user, creds.password)
+	if err != nil {
+		return fmt.Errorf("%w: unable to log in again: %w", ErrSessionExpired, err)
+	}
+	if c.reloginHandler != nil {
+		c.reloginHandler(c)
+	}
+	return nil
+}
+
+//sessionExpired returns true if the response shows that we are no longer logged in,
+//either because we were sent to the login page or because we got a html page instead of json
+func sessionExpired(resp *http.Response, expectJSON bool) bool {
+	if isLoginPath(resp.Request.URL.Path) {
+		return true
+	}
+	ok := resp.StatusCode >= 200 && resp.StatusCode <= 299
+	if ok && expectJSON && strings.HasPrefix(resp.Header.Get("Content-Type"), "text/html") {
+		return true
+	}
+	return false
+}
+
 // GetTimesheet returns a timesheet for an employee spanning the two dates
 func (c *Client) GetTimesheet(employee string, from time.Time, to time.Time) (*TimesheetData, error) {
+	return c.GetTimesheetContext(context.Background(), employee, from, to)
+}
+
+// GetTimesheetContext is like GetTimesheet but with a context
+func (c *Client) GetTimesheetContext(ctx context.Context, employee string, from time.Time, to time.Time) (*TimesheetData, error) {
 	url := "/Time/Timesheet/GetTimeSheetData?employeeId=%s&fromDate=%s&toDate=%s&_=%s"
 	fromString := from.UTC().Format("2006-01-02T15:04:05.000Z")
 	toString := to.UTC().Format("2006-01-02T15:04:05.000Z")
 	timestamp := strconv.Itoa(int(time.Now().UnixNano()))
 
 	url = fmt.Sprintf(url, employee, fromString, toString, timestamp)
-	response, err := c.get(url)
+	response, err := c.get(ctx, url)
 	if err != nil {
 		return nil, err
 	}
 	timesheet := &TimesheetData{}
 
-	err = json.NewDecoder(response.Body).Decode(timesheet)
+	err = c.decode(response, timesheet)
 	if err != nil {
 		return nil, err
 	}
@@ -171,32 +359,65 @@ func (c *Client) GetTimesheet(employee string, from time.Time, to time.Time) (*T
 
 // Login to Qbis
 func (c *Client) Login(company string, user string, password string) error {
+	return c.LoginContext(context.Background(), company, user, password)
+}
+
+// LoginContext is like Login but with a context
+func (c *Client) LoginContext(ctx context.Context, company string, user string, password string) error {
+	// visit the login page first to pick up the cookies set there
+	response, err := c.send(ctx, http.MethodGet, "/Login/Login", "", nil)
+	if err != nil {
+		return err
+	}
+	if err = checkResponse(response); err != nil {
+		return err
+	}
+	response.Body.Close()
+
 	urlvals := make(url.Values)
 	urlvals.Set("Authenticate", "Log+in")
 	urlvals.Set("Company", company)
-	urlvals.Set("CurrentLanguage", "lang_english")
+	urlvals.Set("CurrentLanguage", c.language)
 	urlvals.Set("Password", password)
 	urlvals.Set("RememberMe", "true")
 	urlvals.Set("Username", user)
-	response, err := c.postData("/Login/Login/Authenticate", urlvals)
+	response, err = c.postData(ctx, "/Login/Login/Authenticate", urlvals)
 	if err != nil {
 		return err
 	}
-	if response.StatusCode < 200 || response.StatusCode > 299 {
-		return errors.New("unexpected status code")
+	if err = checkResponse(response); err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	// Qbis answers 200 OK even if the login failed, so we have to look at where we ended up.
+	// A successful login redirects to the time overview which contains the currentLogin block,
+	// a failed one ends up back on the login page.
+	page, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return fmt.Errorf("error reading login response: %v", err)
+	}
+	if hasCurrentLogin(bytes.NewReader(page)) {
+		c.loggedIn(&credentials{company, user, password})
+		return nil
+	}
+
+	path := response.Request.URL.Path
+	if isLoginPath(path) {
+		message := getLoginErrorMessage(bytes.NewReader(page))
+		return &AuthError{Kind: classifyLoginMessage(message), Path: path, Message: message}
 	}
 
-	// TODO implement check to see if login was successful.
-	// currently no error is returned if you enter invalid credentials because you still get a 200 OK
-	/*
-			u, err := response.Location()
-			if err != nil {
-				//TODO handle
-				println(err.Error())
-			}
-			println(u.Path)
-		// https://stackoverflow.com/questions/23297520/how-can-i-make-the-go-http-client-not-follow-redirects-automatically
-	*/
+	// we were sent somewhere else than expected, check if we got a session anyway
+	overview, err := c.send(ctx, http.MethodGet, "/Time/TimeOverview", "", nil)
+	if err != nil {
+		return err
+	}
+	defer overview.Body.Close()
+	if !hasCurrentLogin(overview.Body) {
+		return &AuthError{Kind: AuthUnexpectedPage, Path: strings.Join(redirectChain(response), " -> ")}
+	}
 
+	c.loggedIn(&credentials{company, user, password})
 	return nil
 }
@@ -0,0 +1,37 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+)
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestNewWithReplacedDefaultTransport(t *testing.T) {
+	original := http.DefaultTransport
+	defer func() { http.DefaultTransport = original }()
+
+	replaced := roundTripperFunc(func(*http.Request) (*http.Response, error) {
+		return nil, http.ErrNotSupported
+	})
+	http.DefaultTransport = replaced
+
+	c := New("https://example.com")
+	if _, ok := c.client.Transport.(roundTripperFunc); !ok {
+		t.Fatalf("expected the replaced default transport to be used, got %T", c.client.Transport)
+	}
+}
+
+func TestNewClonesDefaultTransport(t *testing.T) {
+	c := New("https://example.com")
+	if c.client.Transport == http.DefaultTransport {
+		t.Fatal("expected a transport of its own")
+	}
+	if _, ok := c.client.Transport.(*http.Transport); !ok {
+		t.Fatalf("expected a *http.Transport, got %T", c.client.Transport)
+	}
+}
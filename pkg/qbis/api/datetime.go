@@ -1,53 +1,202 @@
 package api
 
 import (
+	"encoding/json"
 	"fmt"
+	"math"
 	"regexp"
 	"strconv"
+	"strings"
 	"time"
 )
 
 //GetDateForDateTime returns the timestamp of the "date"-datetimes used in qbis. Assumes qbis respects local users TZ.
 //QBis represents dates as datetimes with the time set to 00:00:00. They seem to use the local timezone for this.
 //On the wire the datetime is serialized in UTC - this can be confusing because the date of the datetime can change.
+//Use GetDateForDateTimeIn when the process does not run in the time zone of the Qbis user.
 func GetDateForDateTime(date time.Time) (time.Time, error) {
-	/*
-		utc, err := time.LoadLocation("UTC")
-		if err != nil {
-			return date, fmt.Errorf("unable to get UTC location: %v", err)
-		}
-	*/
 	midnightLocalTime := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.Local)
 
 	// We can keep the internal representation in localtime (it's less confusing) and just make sure we serialize the time in UTC before sending to qbis
-	//return midnightLocalTime.In(utc), nil
 	return midnightLocalTime, nil
 }
 
-//DateStringToTime converts a qbis datetime string ( /Date(1520809200000)/ ) to a time
+//GetDateForDateTimeIn is like GetDateForDateTime but for a Qbis user in the time zone loc.
+//The date is the calendar day of the time in loc, and the result is midnight of that day in loc.
+func GetDateForDateTimeIn(date time.Time, loc *time.Location) (time.Time, error) {
+	if loc == nil {
+		return date, fmt.Errorf("no location given")
+	}
+	year, month, day := date.In(loc).Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, loc), nil
+}
+
+//dateStringRegex matches the Microsoft JSON date format: milliseconds since the unix epoch,
+//optionally followed by the utc offset of the local time ( /Date(1520809200000+0100)/ )
+var dateStringRegex = regexp.MustCompile(`^\\?/Date\((-?\d+)(?:([+-])(\d{2})(\d{2}))?\)\\?/$`)
+
+//DateStringToTime converts a qbis datetime string ( /Date(1520809200000)/ ) to a time.
+//The milliseconds are always relative to UTC. If the string has an offset the time is returned in a
+//zone with that offset, otherwise in the local time zone.
 func DateStringToTime(date string) (time.Time, error) {
 	//Example: "/Date(1520809200000)/"
-	re, err := regexp.Compile(`^\/Date\((\d+?)000\)\/$`)
-
-	matches := re.FindStringSubmatch(date)
-	if len(matches) != 2 {
-		return time.Unix(0, 0), fmt.Errorf("error parsing date string")
+	matches := dateStringRegex.FindStringSubmatch(date)
+	if matches == nil {
+		return time.Unix(0, 0), fmt.Errorf("error parsing date string %q", date)
 	}
 
-	posix, err := strconv.ParseInt(matches[1], 10, 64)
+	millis, err := strconv.ParseInt(matches[1], 10, 64)
 	if err != nil {
-		return time.Unix(0, 0), err
+		return time.Unix(0, 0), fmt.Errorf("error parsing date string %q: %v", date, err)
 	}
+	t := time.UnixMilli(millis)
 
-	return time.Unix(posix, 0), nil
+	if matches[2] == "" {
+		return t, nil
+	}
+	hours, _ := strconv.Atoi(matches[3])
+	minutes, _ := strconv.Atoi(matches[4])
+	if hours > 14 || minutes > 59 {
+		return time.Unix(0, 0), fmt.Errorf("error parsing date string %q: invalid offset", date)
+	}
+	offset := hours*60*60 + minutes*60
+	if matches[2] == "-" {
+		offset = -offset
+	}
+	return t.In(time.FixedZone("", offset)), nil
 }
 
-//TimeToDateString returns the a string representing the time in qbis format
+//TimeToDateString returns the a string representing the time in qbis format, with millisecond precision
 func TimeToDateString(t time.Time) string {
-	return fmt.Sprintf("/Date(%d000)/", t.Unix())
+	return fmt.Sprintf("/Date(%d)/", t.UnixMilli())
+}
+
+//TimeToDateStringWithOffset is like TimeToDateString but includes the utc offset of the time ( /Date(1520809200000+0100)/ )
+func TimeToDateStringWithOffset(t time.Time) string {
+	_, offset := t.Zone()
+	sign := '+'
+	if offset < 0 {
+		sign = '-'
+		offset = -offset
+	}
+	return fmt.Sprintf("/Date(%d%c%02d%02d)/", t.UnixMilli(), sign, offset/3600, offset%3600/60)
 }
 
 //TimeToISODateString formats timestamp like javascript
 func TimeToISODateString(t time.Time) string {
 	return t.UTC().Format("2006-01-02T15:04:05.000Z")
 }
+
+//Date is a date or datetime in the qbis api. Qbis sends dates as "/Date(1520809200000)/" but expects
+//ISO 8601 strings when saving, so Date reads both forms and always writes ISO 8601.
+//Empty strings and null are read as the zero Date. A zero Date read from an empty string is written back as one,
+//like qbis sends it for dates that are not set, any other zero Date is written as null.
+type Date struct {
+	time.Time
+
+	//empty is true if the date was read from an empty string
+	empty bool
+}
+
+//NewDate returns the Date of the time
+func NewDate(t time.Time) Date {
+	return Date{Time: t}
+}
+
+//MarshalJSON writes the date as an ISO 8601 string
+func (d Date) MarshalJSON() ([]byte, error) {
+	if d.IsZero() {
+		if d.empty {
+			return []byte(`""`), nil
+		}
+		return []byte("null"), nil
+	}
+	return json.Marshal(TimeToISODateString(d.Time))
+}
+
+//UnmarshalJSON reads "/Date(...)/" and ISO 8601 strings
+func (d *Date) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*d = Date{}
+		return nil
+	}
+	var s string
+	err := json.Unmarshal(data, &s)
+	if err != nil {
+		return fmt.Errorf("invalid date %s: %v", data, err)
+	}
+	t, err := ParseDate(s)
+	if err != nil {
+		return err
+	}
+	*d = Date{Time: t, empty: s == ""}
+	return nil
+}
+
+//isoDateLayouts are the ISO 8601 variants accepted by ParseDate
+var isoDateLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02",
+}
+
+//ParseDate parses a date in any of the forms used by qbis: "/Date(...)/" or ISO 8601.
+//An empty string is the zero time.
+func ParseDate(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if strings.HasPrefix(strings.TrimPrefix(s, "\\"), "/Date(") {
+		return DateStringToTime(s)
+	}
+	for _, layout := range isoDateLayouts {
+		t, err := time.Parse(layout, s)
+		if err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unable to parse date %q", s)
+}
+
+//Minutes is a number of minutes in the qbis api, or minutes since midnight for clock times.
+//It reads numbers, numeric strings and "hh:mm" strings, and writes a json number.
+type Minutes int
+
+//UnmarshalJSON reads numbers, numeric strings and "hh:mm" strings. null and "" are read as 0.
+func (m *Minutes) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if s == "null" {
+		*m = 0
+		return nil
+	}
+	if strings.HasPrefix(s, `"`) {
+		err := json.Unmarshal(data, &s)
+		if err != nil {
+			return err
+		}
+		s = strings.TrimSpace(s)
+		if s == "" {
+			*m = 0
+			return nil
+		}
+		if i := strings.Index(s, ":"); i > 0 {
+			hours, errH := strconv.Atoi(s[:i])
+			minutes, errM := strconv.Atoi(s[i+1:])
+			if errH != nil || errM != nil || minutes < 0 || minutes > 59 {
+				return fmt.Errorf("invalid minutes %q", s)
+			}
+			if hours < 0 || strings.HasPrefix(s, "-") {
+				*m = Minutes(hours*60 - minutes)
+			} else {
+				*m = Minutes(hours*60 + minutes)
+			}
+			return nil
+		}
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return fmt.Errorf("invalid minutes %s", data)
+	}
+	*m = Minutes(math.Round(f))
+	return nil
+}
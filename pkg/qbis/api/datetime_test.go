@@ -0,0 +1,150 @@
+package api
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+//the range of times that can be written as ISO 8601 with a four digit year
+const (
+	minISOMillis = -62135596800000
+	maxISOMillis = 253402300799999
+)
+
+func TestParseDate(t *testing.T) {
+	tests := []struct {
+		s      string
+		millis int64
+		offset string // empty when the time is in the local zone
+	}{
+		{"/Date(1520809200000)/", 1520809200000, ""},
+		{"/Date(1520809200123+0100)/", 1520809200123, "+0100"},
+		{"/Date(1520809200000-0530)/", 1520809200000, "-0530"},
+		{`\/Date(1520809200000+0200)\/`, 1520809200000, "+0200"},
+		{"/Date(-86400000)/", -86400000, ""},
+		{"/Date(-1+0000)/", -1, "+0000"},
+		{"2018-03-11T23:00:00.000Z", 1520809200000, "+0000"},
+		{"2018-03-12T00:00:00+01:00", 1520809200000, "+0100"},
+	}
+	for _, test := range tests {
+		got, err := ParseDate(test.s)
+		if err != nil {
+			t.Errorf("ParseDate(%q): %v", test.s, err)
+			continue
+		}
+		if got.UnixMilli() != test.millis {
+			t.Errorf("ParseDate(%q) = %d ms, want %d", test.s, got.UnixMilli(), test.millis)
+		}
+		if test.offset != "" && got.Format("-0700") != test.offset {
+			t.Errorf("ParseDate(%q) has offset %s, want %s", test.s, got.Format("-0700"), test.offset)
+		}
+	}
+
+	for _, s := range []string{"/Date()/", "/Date(1+2500)/", "/Date(1+0160)/", "/Date(99999999999999999999)/", "Date(1)", "/Date(1)/x", "2018-13-01"} {
+		_, err := ParseDate(s)
+		if err == nil {
+			t.Errorf("ParseDate(%q) did not fail", s)
+		}
+	}
+}
+
+func FuzzParseDate(f *testing.F) {
+	for _, s := range []string{
+		"",
+		"/Date(1520809200000)/",
+		"/Date(1520809200000+0100)/",
+		"/Date(1520809200000-0530)/",
+		`\/Date(1520809200000)\/`,
+		`\/Date(1520809200000+0100)\/`,
+		"/Date(-86400000)/",
+		"/Date(-62135596800000-1400)/",
+		"2018-03-11T23:00:00.000Z",
+		"2018-03-12T00:00:00+01:00",
+		"2018-03-12T00:00:00.123456789",
+		"2018-03-12",
+		"/Date()/",
+		"/Date(1+2500)/",
+	} {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		parsed, err := ParseDate(s)
+		if err != nil {
+			return
+		}
+		if s == "" {
+			if !parsed.IsZero() {
+				t.Fatalf("ParseDate(%q) = %v, want the zero time", s, parsed)
+			}
+			return
+		}
+
+		// qbis dates have millisecond precision
+		millis := time.UnixMilli(parsed.UnixMilli())
+		back, err := ParseDate(TimeToDateString(parsed))
+		if err != nil || !back.Equal(millis) {
+			t.Fatalf("ParseDate(%q) = %v, but %q parses to %v, %v", s, parsed, TimeToDateString(parsed), back, err)
+		}
+		if !strings.HasPrefix(strings.TrimPrefix(s, `\`), "/Date(") {
+			return
+		}
+		if !parsed.Equal(millis) {
+			t.Fatalf("ParseDate(%q) = %v, which is not whole milliseconds", s, parsed)
+		}
+		back, err = ParseDate(TimeToDateStringWithOffset(parsed))
+		if err != nil || !back.Equal(parsed) {
+			t.Fatalf("ParseDate(%q) = %v, but %q parses to %v, %v", s, parsed, TimeToDateStringWithOffset(parsed), back, err)
+		}
+		_, offset := parsed.Zone()
+		if _, got := back.Zone(); got != offset {
+			t.Fatalf("%q has offset %d, want %d", TimeToDateStringWithOffset(parsed), got, offset)
+		}
+	})
+}
+
+func FuzzDateRoundTrip(f *testing.F) {
+	f.Add(int64(1520809200000), 60)
+	f.Add(int64(1520809200123), -330)
+	f.Add(int64(0), 0)
+	f.Add(int64(-86400000), 14*60)
+	f.Add(int64(-1), -14*60)
+	f.Add(int64(minISOMillis), 0)
+	f.Add(int64(maxISOMillis), 0)
+	f.Fuzz(func(t *testing.T, millis int64, offsetMinutes int) {
+		offsetMinutes %= 14*60 + 1
+		date := time.UnixMilli(millis).In(time.FixedZone("", offsetMinutes*60))
+
+		for _, s := range []string{
+			TimeToDateString(date),
+			TimeToDateStringWithOffset(date),
+			`\` + strings.TrimSuffix(TimeToDateStringWithOffset(date), "/") + `\/`,
+		} {
+			parsed, err := ParseDate(s)
+			if err != nil {
+				t.Fatalf("ParseDate(%q): %v", s, err)
+			}
+			if !parsed.Equal(date) {
+				t.Fatalf("ParseDate(%q) = %v, want %v", s, parsed, date)
+			}
+		}
+		parsed, _ := ParseDate(TimeToDateStringWithOffset(date))
+		if _, offset := parsed.Zone(); offset != offsetMinutes*60 {
+			t.Fatalf("%q has offset %d, want %d", TimeToDateStringWithOffset(date), offset, offsetMinutes*60)
+		}
+
+		if millis < minISOMillis || millis > maxISOMillis || date.IsZero() {
+			return
+		}
+		data, err := json.Marshal(NewDate(date))
+		if err != nil {
+			t.Fatalf("Marshal(%v): %v", date, err)
+		}
+		var d Date
+		err = json.Unmarshal(data, &d)
+		if err != nil || !d.Equal(date) {
+			t.Fatalf("%s reads as %v, %v, want %v", data, d.Time, err, date)
+		}
+	})
+}
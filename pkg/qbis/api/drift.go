@@ -0,0 +1,206 @@
+package api
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+//SchemaDrift describes how a Qbis json response differs from the struct it was decoded into.
+//Paths are json property names separated by dots, with [] for array elements, eg. "listOfProjectTime[].Days[].DayDate".
+type SchemaDrift struct {
+	Method   string
+	Endpoint string   // path of the requested resource, without the query
+	Type     string   // the Go type the response was decoded into, eg. "api.TimesheetData"
+	Unknown  []string // properties in the response that the struct does not have
+	Missing  []string // fields of the struct that were not in the response
+}
+
+func (d *SchemaDrift) Error() string {
+	parts := make([]string, 0, 2)
+	if len(d.Unknown) > 0 {
+		parts = append(parts, fmt.Sprintf("unknown fields %s", strings.Join(d.Unknown, ", ")))
+	}
+	if len(d.Missing) > 0 {
+		parts = append(parts, fmt.Sprintf("missing fields %s", strings.Join(d.Missing, ", ")))
+	}
+	return fmt.Sprintf("qbis %s %s: schema drift in %s: %s", d.Method, d.Endpoint, d.Type, strings.Join(parts, "; "))
+}
+
+//SchemaDriftHandler is called when a response does not match the struct it is decoded into.
+//If it returns an error the call fails with an APIError wrapping it, return the drift itself to fail on any drift.
+//Returning nil lets the call succeed with the fields that could be decoded.
+type SchemaDriftHandler func(drift *SchemaDrift) error
+
+//LogSchemaDrift returns a SchemaDriftHandler that logs the drift and lets the call succeed
+func LogSchemaDrift(logger Logger) SchemaDriftHandler {
+	return func(drift *SchemaDrift) error {
+		logger.Printf("%v", drift)
+		return nil
+	}
+}
+
+// WithSchemaDriftHandler turns on strict decoding: every json response is compared to the struct it is decoded into,
+// and differences are reported to the handler. Use it to learn about changes to the Qbis api before they corrupt a save.
+func (c *Client) WithSchemaDriftHandler(handler SchemaDriftHandler) *Client {
+	c.driftHandler = handler
+	return c
+}
+
+var (
+	jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+//schemaDrift compares the json data to the type of v. It returns nil if they match.
+func schemaDrift(data []byte, v interface{}) (*SchemaDrift, error) {
+	var raw interface{}
+	err := json.Unmarshal(data, &raw)
+	if err != nil {
+		return nil, err
+	}
+
+	t := reflect.TypeOf(v)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	d := &driftWalker{unknown: make(map[string]bool), missing: make(map[string]bool)}
+	d.walk("", raw, t)
+	if len(d.unknown) == 0 && len(d.missing) == 0 {
+		return nil, nil
+	}
+
+	return &SchemaDrift{
+		Type:    t.String(),
+		Unknown: sortedKeys(d.unknown),
+		Missing: sortedKeys(d.missing),
+	}, nil
+}
+
+//driftWalker collects the differences between decoded json and a type.
+//Paths are kept in sets, so a field missing from every element of an array is only reported once.
+type driftWalker struct {
+	unknown map[string]bool
+	missing map[string]bool
+}
+
+func (d *driftWalker) walk(path string, raw interface{}, t reflect.Type) {
+	if raw == nil {
+		return
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() == reflect.Interface || isLeaf(t) {
+		return
+	}
+
+	switch value := raw.(type) {
+	case map[string]interface{}:
+		switch t.Kind() {
+		case reflect.Struct:
+			d.walkStruct(path, value, t)
+		case reflect.Map:
+			for k, v := range value {
+				d.walk(join(path, k), v, t.Elem())
+			}
+		}
+	case []interface{}:
+		if t.Kind() != reflect.Slice && t.Kind() != reflect.Array {
+			return
+		}
+		for _, v := range value {
+			d.walk(path+"[]", v, t.Elem())
+		}
+	}
+}
+
+func (d *driftWalker) walkStruct(path string, object map[string]interface{}, t reflect.Type) {
+	fields := jsonFields(t)
+
+	seen := make(map[string]bool)
+	for key, v := range object {
+		field, ok := fields[key]
+		if !ok {
+			// encoding/json falls back to case insensitive matching
+			for name, f := range fields {
+				if strings.EqualFold(name, key) {
+					field, ok = f, true
+					break
+				}
+			}
+		}
+		if !ok {
+			d.unknown[join(path, key)] = true
+			continue
+		}
+		seen[field.Name] = true
+		d.walk(join(path, key), v, field.Type)
+	}
+
+	for name, field := range fields {
+		if !seen[field.Name] {
+			d.missing[join(path, name)] = true
+		}
+	}
+}
+
+//jsonFields returns the fields of the struct by json name, including those of embedded structs
+func jsonFields(t reflect.Type) map[string]reflect.StructField {
+	fields := make(map[string]reflect.StructField)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+
+		ft := f.Type
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if f.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			for n, ef := range jsonFields(ft) {
+				if _, ok := fields[n]; !ok {
+					fields[n] = ef
+				}
+			}
+			continue
+		}
+		if f.PkgPath != "" {
+			continue // unexported
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields[name] = f
+	}
+	return fields
+}
+
+//isLeaf returns true for types that decode themselves, like Date and Minutes
+func isLeaf(t reflect.Type) bool {
+	pt := reflect.PtrTo(t)
+	return pt.Implements(jsonUnmarshalerType) || pt.Implements(textUnmarshalerType)
+}
+
+func join(path string, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
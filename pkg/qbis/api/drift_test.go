@@ -0,0 +1,194 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type driftBase struct {
+	ID int `json:"id"`
+}
+
+type driftRow struct {
+	driftBase
+	Name   string               `json:"name"`
+	Tags   []string             `json:"tags"`
+	ByName map[string]driftBase `json:"byName"`
+	Days   []struct {
+		When  Date    `json:"when"`
+		Spent Minutes `json:"spent"`
+	} `json:"days"`
+	Extra   interface{} `json:"extra"`
+	Untag   bool
+	Skipped int `json:"-"`
+	hidden  int
+}
+
+func TestSchemaDrift(t *testing.T) {
+	const day = `{"when": "/Date(1709506800000)/", "spent": 60}`
+	row := func(fields string) string {
+		return `{"id": 1, "name": "a", "tags": ["x"], "byName": {}, "extra": {"anything": 1}, "Untag": true` + fields + `}`
+	}
+	tests := []struct {
+		name    string
+		json    string
+		unknown []string
+		missing []string
+	}{
+		{"match", row(`, "days": [` + day + `]`), nil, nil},
+		{"null values", `{"id": null, "name": null, "tags": null, "byName": null, "days": null, "extra": null, "Untag": null}`, nil, nil},
+		{"embedded struct", `{"name": "a", "tags": [], "byName": {}, "days": [], "extra": 1, "Untag": true}`, nil, []string{"id"}},
+		{"case insensitive keys", `{"ID": 1, "Name": "a", "TAGS": [], "byname": {}, "Days": [], "extra": 1, "untag": true}`, nil, nil},
+		{"unknown field", row(`, "days": [], "color": "red"`), []string{"color"}, nil},
+		{
+			"slices",
+			row(`, "days": [` + day + `, {"when": "", "spent": 0, "note": ""}, {"spent": 0}]`),
+			[]string{"days[].note"},
+			[]string{"days[].when"},
+		},
+		{
+			"maps",
+			`{"id": 1, "name": "a", "tags": [], "days": [], "extra": 1, "Untag": true, "byName": {"a": {"id": 1, "color": "red"}, "b": {}}}`,
+			[]string{"byName.a.color"},
+			[]string{"byName.b.id"},
+		},
+		{
+			"leaves are not walked into",
+			row(`, "days": [{"when": {"date": 1}, "spent": {"hours": 1}}]`),
+			nil,
+			nil,
+		},
+		{"not an object", `[1, 2]`, nil, nil},
+	}
+	for _, test := range tests {
+		drift, err := schemaDrift([]byte(test.json), &driftRow{})
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if test.unknown == nil && test.missing == nil {
+			if drift != nil {
+				t.Errorf("%s: unexpected drift: %v", test.name, drift)
+			}
+			continue
+		}
+		if drift == nil {
+			t.Errorf("%s: expected drift", test.name)
+			continue
+		}
+		if drift.Type != "api.driftRow" {
+			t.Errorf("%s: type = %q, want api.driftRow", test.name, drift.Type)
+		}
+		if !reflect.DeepEqual(drift.Unknown, append([]string{}, test.unknown...)) {
+			t.Errorf("%s: unknown = %v, want %v", test.name, drift.Unknown, test.unknown)
+		}
+		if !reflect.DeepEqual(drift.Missing, append([]string{}, test.missing...)) {
+			t.Errorf("%s: missing = %v, want %v", test.name, drift.Missing, test.missing)
+		}
+	}
+
+	if _, err := schemaDrift([]byte(`{"id": `), &driftRow{}); err == nil {
+		t.Error("expected an error for invalid json")
+	}
+}
+
+//driftedTimesheet returns the recorded timesheet with an unknown and a missing field in one project day
+func driftedTimesheet(t *testing.T) []byte {
+	t.Helper()
+	recorded, err := ioutil.ReadFile("testdata/timesheet.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sheet map[string]interface{}
+	err = json.Unmarshal(recorded, &sheet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	project := sheet["listOfProjectTime"].([]interface{})[0].(map[string]interface{})
+	day := project["Days"].([]interface{})[1].(map[string]interface{})
+	day["DayColor"] = "#ff0000"
+	delete(day, "DayTime")
+
+	drifted, err := json.Marshal(sheet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return drifted
+}
+
+func TestSchemaDriftInTimesheet(t *testing.T) {
+	drift, err := schemaDrift(driftedTimesheet(t), &TimesheetData{})
+	if err != nil {
+		t.Fatalf("schemaDrift: %v", err)
+	}
+	if drift == nil {
+		t.Fatal("expected drift")
+	}
+	if want := []string{"listOfProjectTime[].Days[].DayColor"}; !reflect.DeepEqual(drift.Unknown, want) {
+		t.Errorf("unknown = %v, want %v", drift.Unknown, want)
+	}
+	if want := []string{"listOfProjectTime[].Days[].DayTime"}; !reflect.DeepEqual(drift.Missing, want) {
+		t.Errorf("missing = %v, want %v", drift.Missing, want)
+	}
+	if drift.Type != "api.TimesheetData" {
+		t.Errorf("type = %q, want api.TimesheetData", drift.Type)
+	}
+}
+
+func TestSchemaDriftHandler(t *testing.T) {
+	drifted := driftedTimesheet(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Write(drifted)
+	}))
+	defer srv.Close()
+	monday := time.Date(2024, time.March, 4, 0, 0, 0, 0, time.UTC)
+
+	var reported *SchemaDrift
+	sheet, err := New(srv.URL).WithSchemaDriftHandler(func(drift *SchemaDrift) error {
+		reported = drift
+		return nil
+	}).GetTimesheet("1234", monday, monday.AddDate(0, 0, 6))
+	if err != nil {
+		t.Fatalf("a handler returning nil should let the call succeed: %v", err)
+	}
+	if len(sheet.ListOfProjectTime) == 0 {
+		t.Error("timesheet was not decoded")
+	}
+	if reported == nil {
+		t.Fatal("handler was not called")
+	}
+	if reported.Method != http.MethodGet || reported.Endpoint != "/Time/Timesheet/GetTimeSheetData" {
+		t.Errorf("drift in %s %s, want GET /Time/Timesheet/GetTimeSheetData", reported.Method, reported.Endpoint)
+	}
+
+	failed := fmt.Errorf("refusing to continue")
+	_, err = New(srv.URL).WithSchemaDriftHandler(func(drift *SchemaDrift) error {
+		return fmt.Errorf("%w: %v", failed, drift)
+	}).GetTimesheet("1234", monday, monday.AddDate(0, 0, 6))
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("expected an *APIError, got %v", err)
+	}
+	if !apiErr.SchemaMismatch() || apiErr.StatusCode != http.StatusOK {
+		t.Errorf("got status %d, schema mismatch %v, want a schema mismatch in a 200 response", apiErr.StatusCode, apiErr.SchemaMismatch())
+	}
+	if !errors.Is(err, failed) {
+		t.Errorf("expected the handler error to be wrapped, got %v", err)
+	}
+
+	_, err = New(srv.URL).WithSchemaDriftHandler(func(drift *SchemaDrift) error {
+		return drift
+	}).GetTimesheet("1234", monday, monday.AddDate(0, 0, 6))
+	var drift *SchemaDrift
+	if !errors.As(err, &drift) || len(drift.Unknown) != 1 || len(drift.Missing) != 1 {
+		t.Errorf("expected the drift to be returned, got %v", err)
+	}
+}
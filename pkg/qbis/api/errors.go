@@ -0,0 +1,125 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+//maxBodyExcerpt is the number of bytes of the response body kept in an APIError
+const maxBodyExcerpt = 512
+
+//APIError is returned when Qbis responds with an unexpected status code or with a body we can't decode.
+//Use errors.As to get at it.
+type APIError struct {
+	StatusCode  int
+	Method      string
+	Endpoint    string // path of the requested resource, without the query
+	ContentType string
+	Body        string // trimmed excerpt of the response body
+	Err         error  // set if the body could not be decoded
+}
+
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("qbis %s %s: status %d", e.Method, e.Endpoint, e.StatusCode)
+	if e.Err != nil {
+		msg += fmt.Sprintf(": unable to decode %s response: %v", e.ContentType, e.Err)
+	}
+	if e.Body != "" {
+		msg += fmt.Sprintf(" (body: %q)", e.Body)
+	}
+	return msg
+}
+
+//Unwrap returns the decoding error, if any
+func (e *APIError) Unwrap() error {
+	return e.Err
+}
+
+//Unauthorized returns true if Qbis refused the request (401 or 403)
+func (e *APIError) Unauthorized() bool {
+	return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden
+}
+
+//ServerError returns true if Qbis failed to handle the request (5xx)
+func (e *APIError) ServerError() bool {
+	return e.StatusCode >= 500 && e.StatusCode <= 599
+}
+
+//SchemaMismatch returns true if the request succeeded but the response did not look like we expected
+func (e *APIError) SchemaMismatch() bool {
+	return e.Err != nil
+}
+
+//newAPIError creates an APIError describing the response
+func newAPIError(resp *http.Response, body []byte, err error) *APIError {
+	req := firstRequest(resp)
+
+	excerpt := strings.Join(strings.Fields(string(body)), " ")
+	if len(excerpt) > maxBodyExcerpt {
+		excerpt = excerpt[:maxBodyExcerpt] + "..."
+	}
+
+	return &APIError{
+		StatusCode:  resp.StatusCode,
+		Method:      req.Method,
+		Endpoint:    req.URL.Path,
+		ContentType: resp.Header.Get("Content-Type"),
+		Body:        excerpt,
+		Err:         err,
+	}
+}
+
+//firstRequest returns the request that was sent, in case we were redirected
+func firstRequest(resp *http.Response) *http.Request {
+	req := resp.Request
+	for req.Response != nil && req.Response.Request != nil {
+		req = req.Response.Request
+	}
+	return req
+}
+
+//checkResponse returns an APIError and closes the body if the response does not have a 2xx status code
+func checkResponse(resp *http.Response) error {
+	if resp.StatusCode >= 200 && resp.StatusCode <= 299 {
+		return nil
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(resp.Body)
+	return newAPIError(resp, body, nil)
+}
+
+//decode reads the json response into v and closes the body.
+//With a schema drift handler set, the response is also compared to the type of v.
+func (c *Client) decode(resp *http.Response, v interface{}) error {
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("error reading response body: %v", err)
+	}
+
+	err = json.Unmarshal(body, v)
+	if err != nil {
+		return newAPIError(resp, body, err)
+	}
+
+	if c.driftHandler != nil {
+		drift, err := schemaDrift(body, v)
+		if err != nil {
+			return newAPIError(resp, body, err)
+		}
+		if drift != nil {
+			req := firstRequest(resp)
+			drift.Method = req.Method
+			drift.Endpoint = req.URL.Path
+			err = c.driftHandler(drift)
+			if err != nil {
+				return newAPIError(resp, body, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAPIError(t *testing.T) {
+	tests := []struct {
+		status       int
+		body         string
+		wantBody     string
+		serverError  bool
+		unauthorized bool
+	}{
+		{http.StatusInternalServerError, "  <h1>Server   Error</h1>\n\n in '/' Application. ", "<h1>Server Error</h1> in '/' Application.", true, false},
+		{http.StatusForbidden, "Forbidden\r\n", "Forbidden", false, true},
+		{http.StatusBadGateway, strings.Repeat("x", 2*maxBodyExcerpt), strings.Repeat("x", maxBodyExcerpt) + "...", true, false},
+	}
+	for _, test := range tests {
+		t.Run(http.StatusText(test.status), func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/html; charset=utf-8")
+				w.WriteHeader(test.status)
+				w.Write([]byte(test.body))
+			}))
+			defer srv.Close()
+
+			monday := time.Date(2024, time.March, 4, 0, 0, 0, 0, time.UTC)
+			_, err := New(srv.URL).GetTimesheet("1234", monday, monday.AddDate(0, 0, 6))
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("expected an *APIError, got %v", err)
+			}
+			if apiErr.StatusCode != test.status || apiErr.Method != http.MethodGet {
+				t.Errorf("got %s with status %d, want GET with status %d", apiErr.Method, apiErr.StatusCode, test.status)
+			}
+			if apiErr.Endpoint != "/Time/Timesheet/GetTimeSheetData" {
+				t.Errorf("endpoint = %q, want the path without the query", apiErr.Endpoint)
+			}
+			if apiErr.Body != test.wantBody {
+				t.Errorf("body = %q, want %q", apiErr.Body, test.wantBody)
+			}
+			if apiErr.ServerError() != test.serverError || apiErr.Unauthorized() != test.unauthorized {
+				t.Errorf("server error %v, unauthorized %v, want %v and %v", apiErr.ServerError(), apiErr.Unauthorized(), test.serverError, test.unauthorized)
+			}
+			if apiErr.SchemaMismatch() {
+				t.Error("a status error is not a schema mismatch")
+			}
+		})
+	}
+}
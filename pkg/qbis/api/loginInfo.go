@@ -0,0 +1,433 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+//LoginInfo is the profile of the logged in user, as found in the currentLogin block on the time overview.
+//If the block can't be parsed in full, only EmployeeID is set.
+type LoginInfo struct {
+	EmployeeID string
+	UserName   string
+	Company    string
+	Language   string
+
+	// Permissions holds the boolean flags of the block (eg. isAllowedToRegisterWorkingTimeFromWeb),
+	// including those in a nested permissions object
+	Permissions map[string]bool
+
+	// Raw holds every property of the block, decoded like encoding/json decodes into an interface{}:
+	// strings, float64, bool, nil, []interface{} and map[string]interface{}.
+	// Values that are not literals (eg. function calls) are kept as their javascript source.
+	Raw map[string]interface{}
+}
+
+//Allowed returns true if the permission flag is set
+func (l *LoginInfo) Allowed(permission string) bool {
+	return l.Permissions[permission]
+}
+
+//loginInfoKeys are the property names that may hold each LoginInfo field, in order of preference
+var loginInfoKeys = struct {
+	employeeID, userName, company, language, permissions []string
+}{
+	employeeID:  []string{"currentUser", "employeeId", "employeeID"},
+	userName:    []string{"userName", "username", "name"},
+	company:     []string{"companyName", "company"},
+	language:    []string{"language", "lang", "currentLanguage"},
+	permissions: []string{"permissions", "rights"},
+}
+
+//GetLoginInfo returns the profile of the logged in user
+func (c *Client) GetLoginInfo() (*LoginInfo, error) {
+	return c.GetLoginInfoContext(context.Background())
+}
+
+//GetLoginInfoContext is like GetLoginInfo but with a context
+func (c *Client) GetLoginInfoContext(ctx context.Context) (*LoginInfo, error) {
+	res, err := c.getPage(ctx, "/Time/TimeOverview")
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	return getLoginInfoFromPage(res.Body)
+}
+
+//getLoginInfoFromPage looks for the currentLogin block in the scripts of the html page
+func getLoginInfoFromPage(r io.Reader) (*LoginInfo, error) {
+	scripts, err := getEmbeddedScriptsInHTML(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(scripts) == 0 {
+		return nil, fmt.Errorf("error loading scripts from page")
+	}
+
+	var lastErr error
+	for _, script := range scripts {
+		info, err := getLoginInfoFromScript(script)
+		if err == nil {
+			return info, nil
+		}
+		if err != errNoCurrentLogin {
+			lastErr = err
+		}
+	}
+	if lastErr != nil {
+		return nil, lastErr
+	}
+	return nil, errNoCurrentLogin
+}
+
+var errNoCurrentLogin = fmt.Errorf("unable to find currentLogin block in scripts")
+
+var currentLoginRegex = regexp.MustCompile(`currentLogin\s*=\s*\{`)
+
+//currentUserRegex finds the employee ID in a currentLogin block that can't be parsed
+var currentUserRegex = regexp.MustCompile(`\bcurrentUser\s*:\s*['"]?(\d+)`)
+
+//hasCurrentLogin returns true if one of the scripts of the html page has a currentLogin block,
+//which is only on pages for logged in users
+func hasCurrentLogin(r io.Reader) bool {
+	scripts, err := getEmbeddedScriptsInHTML(r)
+	if err != nil {
+		return false
+	}
+	for _, script := range scripts {
+		if currentLoginRegex.MatchString(script) {
+			return true
+		}
+	}
+	return false
+}
+
+//getLoginInfoFromScript parses the currentLogin object in the javascript
+func getLoginInfoFromScript(script string) (*LoginInfo, error) {
+	loc := currentLoginRegex.FindStringIndex(script)
+	if loc == nil {
+		return nil, errNoCurrentLogin
+	}
+
+	p := &jsParser{src: script, pos: loc[1] - 1}
+	raw, err := p.object()
+	if err != nil {
+		// a single property we can't read should not keep anyone from logging in, so fall back to just the employee ID
+		matches := currentUserRegex.FindStringSubmatch(script[loc[1]:])
+		if matches == nil {
+			return nil, fmt.Errorf("unable to parse currentLogin block: %v", err)
+		}
+		return &LoginInfo{
+			EmployeeID:  matches[1],
+			Permissions: make(map[string]bool),
+			Raw:         map[string]interface{}{"currentUser": matches[1]},
+		}, nil
+	}
+
+	info := &LoginInfo{
+		EmployeeID:  lookupString(raw, loginInfoKeys.employeeID),
+		UserName:    lookupString(raw, loginInfoKeys.userName),
+		Company:     lookupString(raw, loginInfoKeys.company),
+		Language:    lookupString(raw, loginInfoKeys.language),
+		Permissions: make(map[string]bool),
+		Raw:         raw,
+	}
+	if info.EmployeeID == "" {
+		return nil, fmt.Errorf("currentLogin block has no currentUser")
+	}
+
+	for k, v := range raw {
+		if b, ok := v.(bool); ok {
+			info.Permissions[k] = b
+		}
+	}
+	for _, key := range loginInfoKeys.permissions {
+		nested, ok := raw[key].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for k, v := range nested {
+			if b, ok := v.(bool); ok {
+				info.Permissions[k] = b
+			}
+		}
+	}
+
+	return info, nil
+}
+
+//lookupString returns the first of the keys found in the object, formatted as a string
+func lookupString(object map[string]interface{}, keys []string) string {
+	for _, key := range keys {
+		switch v := object[key].(type) {
+		case string:
+			return v
+		case float64:
+			return strconv.FormatFloat(v, 'f', -1, 64)
+		}
+	}
+	return ""
+}
+
+//jsParser reads javascript object literals
+type jsParser struct {
+	src string
+	pos int
+}
+
+func (p *jsParser) errorf(format string, v ...interface{}) error {
+	return fmt.Errorf("offset %d: %s", p.pos, fmt.Sprintf(format, v...))
+}
+
+//skipSpace skips whitespace and comments
+func (p *jsParser) skipSpace() {
+	for p.pos < len(p.src) {
+		switch {
+		case strings.HasPrefix(p.src[p.pos:], "//"):
+			end := strings.IndexByte(p.src[p.pos:], '\n')
+			if end < 0 {
+				p.pos = len(p.src)
+				return
+			}
+			p.pos += end + 1
+		case strings.HasPrefix(p.src[p.pos:], "/*"):
+			end := strings.Index(p.src[p.pos+2:], "*/")
+			if end < 0 {
+				p.pos = len(p.src)
+				return
+			}
+			p.pos += end + 4
+		case unicode.IsSpace(rune(p.src[p.pos])):
+			p.pos++
+		default:
+			return
+		}
+	}
+}
+
+//peek returns the next non space byte, or 0 at the end of the source
+func (p *jsParser) peek() byte {
+	p.skipSpace()
+	if p.pos >= len(p.src) {
+		return 0
+	}
+	return p.src[p.pos]
+}
+
+func (p *jsParser) value() (interface{}, error) {
+	switch c := p.peek(); {
+	case c == 0:
+		return nil, p.errorf("unexpected end of script")
+	case c == '{':
+		return p.object()
+	case c == '[':
+		return p.array()
+	case c == '\'' || c == '"' || c == '`':
+		return p.string()
+	case c == '-' || c == '+' || c == '.' || (c >= '0' && c <= '9'):
+		start := p.pos
+		raw := p.expression()
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			p.pos = start
+			return p.expression(), nil
+		}
+		return f, nil
+	default:
+		raw := p.expression()
+		switch raw {
+		case "true", "!0":
+			return true, nil
+		case "false", "!1":
+			return false, nil
+		case "null", "undefined":
+			return nil, nil
+		case "":
+			return nil, p.errorf("unexpected %q", c)
+		}
+		return raw, nil
+	}
+}
+
+func (p *jsParser) object() (map[string]interface{}, error) {
+	if p.peek() != '{' {
+		return nil, p.errorf("expected {")
+	}
+	p.pos++
+
+	object := make(map[string]interface{})
+	for {
+		if p.peek() == '}' {
+			p.pos++
+			return object, nil
+		}
+
+		key, err := p.key()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ':' {
+			return nil, p.errorf("expected : after %q", key)
+		}
+		p.pos++
+
+		object[key], err = p.value()
+		if err != nil {
+			return nil, err
+		}
+
+		switch p.peek() {
+		case ',':
+			p.pos++
+		case '}':
+		default:
+			return nil, p.errorf("expected , or } after %q", key)
+		}
+	}
+}
+
+func (p *jsParser) array() ([]interface{}, error) {
+	p.pos++
+
+	array := make([]interface{}, 0)
+	for {
+		if p.peek() == ']' {
+			p.pos++
+			return array, nil
+		}
+
+		v, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		array = append(array, v)
+
+		switch p.peek() {
+		case ',':
+			p.pos++
+		case ']':
+		default:
+			return nil, p.errorf("expected , or ]")
+		}
+	}
+}
+
+//key reads a property name: an identifier, a string or a number
+func (p *jsParser) key() (string, error) {
+	switch c := p.peek(); c {
+	case '\'', '"':
+		return p.string()
+	case 0:
+		return "", p.errorf("unexpected end of script")
+	}
+
+	start := p.pos
+	for p.pos < len(p.src) {
+		r, size := utf8.DecodeRuneInString(p.src[p.pos:])
+		if r != '_' && r != '$' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			break
+		}
+		p.pos += size
+	}
+	if start == p.pos {
+		return "", p.errorf("expected property name")
+	}
+	return p.src[start:p.pos], nil
+}
+
+//string reads a quoted string, resolving escapes
+func (p *jsParser) string() (string, error) {
+	quote := p.src[p.pos]
+	p.pos++
+
+	var b strings.Builder
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		p.pos++
+		switch c {
+		case quote:
+			return b.String(), nil
+		case '\\':
+			if p.pos >= len(p.src) {
+				break
+			}
+			e := p.src[p.pos]
+			p.pos++
+			switch e {
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			case 'b':
+				b.WriteByte('\b')
+			case 'f':
+				b.WriteByte('\f')
+			case 'v':
+				b.WriteByte('\v')
+			case '0':
+				b.WriteByte(0)
+			case 'x', 'u':
+				n := 2
+				if e == 'u' {
+					n = 4
+				}
+				if p.pos+n > len(p.src) {
+					return "", p.errorf("invalid escape")
+				}
+				code, err := strconv.ParseUint(p.src[p.pos:p.pos+n], 16, 32)
+				if err != nil {
+					return "", p.errorf("invalid escape: %v", err)
+				}
+				p.pos += n
+				b.WriteRune(rune(code))
+			case '\n':
+				// line continuation
+			default:
+				b.WriteByte(e)
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", p.errorf("unterminated string")
+}
+
+//expression reads the source of a value up to the next , } or ] outside of brackets and strings
+func (p *jsParser) expression() string {
+	start := p.pos
+	depth := 0
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		switch c {
+		case '\'', '"', '`':
+			if _, err := p.string(); err != nil {
+				return strings.TrimSpace(p.src[start:p.pos])
+			}
+			continue
+		case '(', '[', '{':
+			depth++
+		case ')':
+			depth--
+		case ']', '}':
+			if depth == 0 {
+				return strings.TrimSpace(p.src[start:p.pos])
+			}
+			depth--
+		case ',', ';', '\n':
+			if depth == 0 {
+				return strings.TrimSpace(p.src[start:p.pos])
+			}
+		}
+		p.pos++
+	}
+	return strings.TrimSpace(p.src[start:p.pos])
+}
@@ -0,0 +1,157 @@
+package api
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestJSParserValue(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want interface{}
+	}{
+		{"single quotes", `'say "hi"'`, `say "hi"`},
+		{"double quotes", `"it's"`, "it's"},
+		{"backticks", "`say \"hi\"`", `say "hi"`},
+		{"escaped quote", `'it\'s'`, "it's"},
+		{"escapes", `"a\tb\nc\\d"`, "a\tb\nc\\d"},
+		{"hex and unicode escapes", `'\x41\u00e5Ä'`, "AåÄ"},
+		{"line continuation", "'a\\\nb'", "ab"},
+		{"number", `-1.5`, -1.5},
+		{"id", `1234`, 1234.0},
+		{"true", `true`, true},
+		{"minified true", `!0`, true},
+		{"minified false", `!1`, false},
+		{"null", `null`, nil},
+		{"undefined", `undefined`, nil},
+		{"array", `[1, 'two', [!0]]`, []interface{}{1.0, "two", []interface{}{true}}},
+		{"trailing commas", `{a: [1,], }`, map[string]interface{}{"a": []interface{}{1.0}}},
+		{
+			"nested objects",
+			`{ 'quoted': { "double": { deep: 1 } }, $id_2: {} }`,
+			map[string]interface{}{
+				"quoted": map[string]interface{}{"double": map[string]interface{}{"deep": 1.0}},
+				"$id_2":  map[string]interface{}{},
+			},
+		},
+		{
+			"function values",
+			`{ f: function (a, b) { return [a, b]; }, call: parseInt('1,2', 10), x: 1 }`,
+			map[string]interface{}{"f": "function (a, b) { return [a, b]; }", "call": "parseInt('1,2', 10)", "x": 1.0},
+		},
+		{"expression", `{ d: new Date(2024, 2, 4) }`, map[string]interface{}{"d": "new Date(2024, 2, 4)"}},
+		{
+			"comments",
+			"{ // the user\n a: /* first */ 1, /* b: 2, */\n c: 'not // a comment' }",
+			map[string]interface{}{"a": 1.0, "c": "not // a comment"},
+		},
+	}
+	for _, test := range tests {
+		p := &jsParser{src: test.src}
+		got, err := p.value()
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %#v, want %#v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestJSParserErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+	}{
+		{"regular expression", `{ re: /x,y/ }`},
+		{"unterminated string", `{ a: 'abc }`},
+		{"unterminated object", `{ a: 1`},
+		{"invalid escape", `{ a: '\u00' }`},
+		{"missing colon", `{ a 1 }`},
+		{"missing value", `{ a: }`},
+	}
+	for _, test := range tests {
+		p := &jsParser{src: test.src}
+		if v, err := p.object(); err == nil {
+			t.Errorf("%s: expected an error, got %#v", test.name, v)
+		}
+	}
+}
+
+func TestGetLoginInfoFromScript(t *testing.T) {
+	tests := []struct {
+		name        string
+		script      string
+		employeeID  string
+		userName    string
+		permissions map[string]bool
+		err         bool
+	}{
+		{
+			name: "block",
+			script: `var x = 1; var currentLogin = { currentUser: '1234', userName: "Jane Doe",
+				isAllowedToRegisterWorkingTimeFromWeb: !0, permissions: { canApprove: !1 } };`,
+			employeeID:  "1234",
+			userName:    "Jane Doe",
+			permissions: map[string]bool{"isAllowedToRegisterWorkingTimeFromWeb": true, "canApprove": false},
+		},
+		{
+			name:        "unparseable value falls back to currentUser",
+			script:      `currentLogin = { userName: 'Jane', re: /x,y/, currentUser: '1234' };`,
+			employeeID:  "1234",
+			permissions: map[string]bool{},
+		},
+		{
+			name:        "unparseable value falls back to a numeric currentUser",
+			script:      `currentLogin={re:/x,y/,currentUser:1234}`,
+			employeeID:  "1234",
+			permissions: map[string]bool{},
+		},
+		{name: "unparseable without currentUser", script: `currentLogin = { re: /x,y/ };`, err: true},
+		{name: "no currentUser", script: `currentLogin = { userName: 'Jane' };`, err: true},
+		{name: "no block", script: `var currentUser = '1234';`, err: true},
+	}
+	for _, test := range tests {
+		info, err := getLoginInfoFromScript(test.script)
+		if test.err {
+			if err == nil {
+				t.Errorf("%s: expected an error, got %+v", test.name, info)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if info.EmployeeID != test.employeeID || info.UserName != test.userName {
+			t.Errorf("%s: got employee %q named %q, want %q named %q", test.name, info.EmployeeID, info.UserName, test.employeeID, test.userName)
+		}
+		if !reflect.DeepEqual(info.Permissions, test.permissions) {
+			t.Errorf("%s: got permissions %v, want %v", test.name, info.Permissions, test.permissions)
+		}
+	}
+}
+
+func TestHasCurrentLogin(t *testing.T) {
+	page := func(script string) string {
+		return "<html><head><script>" + script + "</script></head><body></body></html>"
+	}
+	tests := []struct {
+		name string
+		html string
+		want bool
+	}{
+		{"block", page(`var currentLogin = { currentUser: '1234' };`), true},
+		{"unparseable block", page(`var currentLogin = { re: /x,y/ };`), true},
+		{"login page", page(`var loginFailed = true;`), false},
+		{"block outside of scripts", "<html><body>currentLogin = {}</body></html>", false},
+	}
+	for _, test := range tests {
+		if got := hasCurrentLogin(strings.NewReader(test.html)); got != test.want {
+			t.Errorf("%s: got %v, want %v", test.name, got, test.want)
+		}
+	}
+}
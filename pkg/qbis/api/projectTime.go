@@ -2,7 +2,7 @@ package api
 
 import (
 	"bytes"
-	"encoding/json"
+	"context"
 	"fmt"
 	"strconv"
 	"time"
@@ -20,26 +20,26 @@ type ProjectActivityListItem struct {
 //SaveProjectTimeResponse represents the response object when saving ProjectTime
 type SaveProjectTimeResponse struct {
 	DuplicatedActivitiesError string        `json:"duplicatedActivitiesError"`
-	LimitErrorResults         []interface{} `json:"limitErrorResults"`
-	LimitWarningResults       []interface{} `json:"limitWarningResults"`
+	LimitErrorResults         []LimitResult `json:"limitErrorResults"`
+	LimitWarningResults       []LimitResult `json:"limitWarningResults"`
 	WasSaved                  bool          `json:"wasSaved"`
 }
 
 //EmployeeProjectTime represents time spent by employee on project activities
 type EmployeeProjectTime struct {
 	EmployeeID string        `json:"employeeId"`
-	FromDate   string        `json:"fromDate"`
+	FromDate   Date          `json:"fromDate"`
 	List       []ProjectTime `json:"list"`
-	ToDate     string        `json:"toDate"`
+	ToDate     Date          `json:"toDate"`
 }
 
 // ProjectTime ...
 type ProjectTime struct {
 	ProjectTimeBase
 	Days []struct {
-		DayDate                   string      `json:"DayDate"`
+		DayDate                   Date        `json:"DayDate"`
 		DayID                     int         `json:"DayId"`
-		DayMinutes                int         `json:"DayMinutes"`
+		DayMinutes                Minutes     `json:"DayMinutes"`
 		DayTime                   string      `json:"DayTime"`
 		ExternalNotes             string      `json:"ExternalNotes"`
 		InternalNotes             string      `json:"InternalNotes"`
@@ -63,7 +63,7 @@ type ProjectTimeBase struct {
 	CustomerFullName            string `json:"CustomerFullName"`
 	CustomerName                string `json:"CustomerName"`
 	CustomerProjectActivityName string `json:"CustomerProjectActivityName"`
-	EndDate                     string `json:"EndDate"`
+	EndDate                     Date   `json:"EndDate"`
 	FixedPrice                  bool   `json:"FixedPrice"`
 	FromServiceRequest          bool   `json:"FromServiceRequest"`
 	IsDeleteable                bool   `json:"IsDeleteable"`
@@ -78,7 +78,7 @@ type ProjectTimeBase struct {
 	ProjectTimeApprovedBy       string `json:"ProjectTimeApprovedBy"`
 	ReadOnlyColor               string `json:"ReadOnlyColor"`
 	ReadOnlyFactorColor         string `json:"ReadOnlyFactorColor"`
-	StartDate                   string `json:"StartDate"`
+	StartDate                   Date   `json:"StartDate"`
 	Week                        string `json:"Week"`
 	YearWeek                    int    `json:"YearWeek"`
 }
@@ -121,6 +121,11 @@ func (a ProjectActivityOverview) SpentMinutes() (uint, error) {
 
 //GetProjects ....
 func (c *Client) GetProjects(employee string, from time.Time, to time.Time) ([]ProjectCompany, error) {
+	return c.GetProjectsContext(context.Background(), employee, from, to)
+}
+
+//GetProjectsContext is like GetProjects but with a context
+func (c *Client) GetProjectsContext(ctx context.Context, employee string, from time.Time, to time.Time) ([]ProjectCompany, error) {
 	url := "/Time/TimesheetProjectTime/GetCustomerProjectDropDown?employeeId=%s&fromDate=%s&toDate=%s&selectedID=%s&_=%s"
 
 	fromString := from.UTC().Format("2006-01-02T15:04:05.000Z")
@@ -130,13 +135,13 @@ func (c *Client) GetProjects(employee string, from time.Time, to time.Time) ([]P
 	selected := "0"
 
 	url = fmt.Sprintf(url, employee, fromString, toString, selected, timestamp)
-	response, err := c.get(url)
+	response, err := c.get(ctx, url)
 	if err != nil {
 		return nil, err
 	}
 	projectCompanies := make([]ProjectCompany, 0)
 
-	err = json.NewDecoder(response.Body).Decode(&projectCompanies)
+	err = c.decode(response, &projectCompanies)
 	if err != nil {
 		return nil, err
 	}
@@ -146,6 +151,11 @@ func (c *Client) GetProjects(employee string, from time.Time, to time.Time) ([]P
 
 //GetProjectActivityList returns a slice of activities for the given project id
 func (c *Client) GetProjectActivityList(employee string, projectID int, from time.Time, to time.Time) ([]ProjectActivityListItem, error) {
+	return c.GetProjectActivityListContext(context.Background(), employee, projectID, from, to)
+}
+
+//GetProjectActivityListContext is like GetProjectActivityList but with a context
+func (c *Client) GetProjectActivityListContext(ctx context.Context, employee string, projectID int, from time.Time, to time.Time) ([]ProjectActivityListItem, error) {
 	url := "/Time/TimesheetProjectTime/GetActivityDropDown?employeeId=%s&fromDate=%s&toDate=%s&selectedID=%s&projectID=%d&_=%s"
 
 	fromString := from.UTC().Format("2006-01-02T15:04:05.000Z")
@@ -155,13 +165,13 @@ func (c *Client) GetProjectActivityList(employee string, projectID int, from tim
 	selected := "0"
 
 	url = fmt.Sprintf(url, employee, fromString, toString, selected, projectID, timestamp)
-	response, err := c.get(url)
+	response, err := c.get(ctx, url)
 	if err != nil {
 		return nil, err
 	}
 
 	activies := make([]ProjectActivityListItem, 0)
-	err = json.NewDecoder(response.Body).Decode(&activies)
+	err = c.decode(response, &activies)
 	if err != nil {
 		return nil, err
 	}
@@ -171,6 +181,11 @@ func (c *Client) GetProjectActivityList(employee string, projectID int, from tim
 
 //GetProjectActivity ...
 func (c *Client) GetProjectActivity(employee string, activityID int, from time.Time, to time.Time) (*ProjectTime, error) {
+	return c.GetProjectActivityContext(context.Background(), employee, activityID, from, to)
+}
+
+//GetProjectActivityContext is like GetProjectActivity but with a context
+func (c *Client) GetProjectActivityContext(ctx context.Context, employee string, activityID int, from time.Time, to time.Time) (*ProjectTime, error) {
 	url := "/Time/TimesheetProjectTime/GetActivityInformation?activityId=%d&employeeId=%s&fromDate=%s&toDate=%s&_=%s"
 
 	fromString := from.UTC().Format("2006-01-02T15:04:05.000Z")
@@ -178,13 +193,13 @@ func (c *Client) GetProjectActivity(employee string, activityID int, from time.T
 	timestamp := strconv.Itoa(int(time.Now().UnixNano()))
 
 	url = fmt.Sprintf(url, activityID, employee, fromString, toString, timestamp)
-	response, err := c.get(url)
+	response, err := c.get(ctx, url)
 	if err != nil {
 		return nil, err
 	}
 
 	var details ProjectTime
-	err = json.NewDecoder(response.Body).Decode(&details)
+	err = c.decode(response, &details)
 	if err != nil {
 		return nil, err
 	}
@@ -194,6 +209,11 @@ func (c *Client) GetProjectActivity(employee string, activityID int, from time.T
 
 //GetProjectActivityOverview ..
 func (c *Client) GetProjectActivityOverview(employee string, activityID int, from time.Time, to time.Time) (*ProjectActivityOverview, error) {
+	return c.GetProjectActivityOverviewContext(context.Background(), employee, activityID, from, to)
+}
+
+//GetProjectActivityOverviewContext is like GetProjectActivityOverview but with a context
+func (c *Client) GetProjectActivityOverviewContext(ctx context.Context, employee string, activityID int, from time.Time, to time.Time) (*ProjectActivityOverview, error) {
 	url := "/Time/TimesheetProjectTime/GetActivityOverview?activityId=%d&employeeId=%s&fromDate=%s&toDate=%s&_=%s"
 
 	fromString := from.UTC().Format("2006-01-02T15:04:05.000Z")
@@ -201,12 +221,12 @@ func (c *Client) GetProjectActivityOverview(employee string, activityID int, fro
 	timestamp := strconv.Itoa(int(time.Now().UnixNano()))
 
 	url = fmt.Sprintf(url, activityID, employee, fromString, toString, timestamp)
-	response, err := c.get(url)
+	response, err := c.get(ctx, url)
 	if err != nil {
 		return nil, err
 	}
 	var overview ProjectActivityOverview
-	err = json.NewDecoder(response.Body).Decode(&overview)
+	err = c.decode(response, &overview)
 	if err != nil {
 		return nil, err
 	}
@@ -216,17 +236,21 @@ func (c *Client) GetProjectActivityOverview(employee string, activityID int, fro
 
 //SaveProjectTime saves the matrix with hours spent on project activities
 func (c *Client) SaveProjectTime(time EmployeeProjectTime) (*SaveProjectTimeResponse, error) {
-	var b bytes.Buffer
-	err := json.NewEncoder(&b).Encode(time)
+	return c.SaveProjectTimeContext(context.Background(), time)
+}
+
+//SaveProjectTimeContext is like SaveProjectTime but with a context
+func (c *Client) SaveProjectTimeContext(ctx context.Context, time EmployeeProjectTime) (*SaveProjectTimeResponse, error) {
+	body, err := EncodePayload(time)
 	if err != nil {
 		return nil, err
 	}
-	response, err := c.postJSON("/Time/TimesheetProjectTime/SaveProjectTime", &b)
+	response, err := c.postJSON(ctx, "/Time/TimesheetProjectTime/SaveProjectTime", bytes.NewReader(body))
 	if err != nil {
 		return nil, err
 	}
 	var saveResponse SaveProjectTimeResponse
-	err = json.NewDecoder(response.Body).Decode(&saveResponse)
+	err = c.decode(response, &saveResponse)
 	if err != nil {
 		return nil, err
 	}
@@ -2,9 +2,8 @@ package api
 
 import (
 	"bytes"
-	"encoding/json"
+	"context"
 	"fmt"
-	"io/ioutil"
 	"strconv"
 	"time"
 )
@@ -49,8 +48,8 @@ type SalaryTimeBase struct {
 //EmployeeSalaryTime struct represents the payload used when saving the matrix containing overtime, sick-time etc.
 type EmployeeSalaryTime struct {
 	EmployeeID        string             `json:"employeeId"`
-	FromDate          string             `json:"fromDate"`
-	ToDate            string             `json:"toDate"`
+	FromDate          Date               `json:"fromDate"`
+	ToDate            Date               `json:"toDate"`
 	SalaryTime        []SalaryTimeBase   `json:"salaryTime"`
 	WorkingTime       []WorkingTimeBase  `json:"workingTime"`
 	WorkingTimeBreaks []WorkingTimeBreak `json:"workingTimeBreaks"`
@@ -61,8 +60,8 @@ type SaveSalaryTimeResponse struct {
 	FromToValidationResult  string        `json:"fromToValidationResult"`
 	InvalidFullDaysError    string        `json:"invalidFullDaysError"`
 	InvalidPartialDaysError string        `json:"invalidPartialDaysError"`
-	LimitErrorResults       []interface{} `json:"limitErrorResults"`
-	LimitWarningResults     []interface{} `json:"limitWarningResults"`
+	LimitErrorResults       []LimitResult `json:"limitErrorResults"`
+	LimitWarningResults     []LimitResult `json:"limitWarningResults"`
 	ResetWarning            string        `json:"resetWarning"`
 	WasSaved                bool          `json:"wasSaved"`
 }
@@ -90,6 +89,11 @@ func (a SalaryActivityOverview) SpentMinutes() (uint, error) {
 
 //GetSalaryActivityOverview ..
 func (c *Client) GetSalaryActivityOverview(employee string, activityID int, from time.Time, to time.Time) (*SalaryActivityOverview, error) {
+	return c.GetSalaryActivityOverviewContext(context.Background(), employee, activityID, from, to)
+}
+
+//GetSalaryActivityOverviewContext is like GetSalaryActivityOverview but with a context
+func (c *Client) GetSalaryActivityOverviewContext(ctx context.Context, employee string, activityID int, from time.Time, to time.Time) (*SalaryActivityOverview, error) {
 	url := "/Time/TimesheetSalaryTime/GetActivityOverview?activityId=%d&employeeId=%s&fromDate=%s&toDate=%s&_=%s"
 
 	fromString := from.UTC().Format("2006-01-02T15:04:05.000Z")
@@ -97,12 +101,12 @@ func (c *Client) GetSalaryActivityOverview(employee string, activityID int, from
 	timestamp := strconv.Itoa(int(time.Now().UnixNano()))
 
 	url = fmt.Sprintf(url, activityID, employee, fromString, toString, timestamp)
-	response, err := c.get(url)
+	response, err := c.get(ctx, url)
 	if err != nil {
 		return nil, err
 	}
 	var overview SalaryActivityOverview
-	err = json.NewDecoder(response.Body).Decode(&overview)
+	err = c.decode(response, &overview)
 	if err != nil {
 		return nil, err
 	}
@@ -112,32 +116,24 @@ func (c *Client) GetSalaryActivityOverview(employee string, activityID int, from
 
 //SaveSalaryTime saves the matrix with hours of overtime, sickleave, timebank etc.
 func (c *Client) SaveSalaryTime(time EmployeeSalaryTime) (*SaveSalaryTimeResponse, error) {
+	return c.SaveSalaryTimeContext(context.Background(), time)
+}
 
-	var b bytes.Buffer
-	err := json.NewEncoder(&b).Encode(time)
+//SaveSalaryTimeContext is like SaveSalaryTime but with a context
+func (c *Client) SaveSalaryTimeContext(ctx context.Context, time EmployeeSalaryTime) (*SaveSalaryTimeResponse, error) {
+
+	body, err := EncodePayload(time)
 	if err != nil {
 		return nil, err
 	}
 	//fmt.Printf("payload: \n%s\n", b.String())
-	response, err := c.postJSON("/Time/TimesheetSalaryTime/SaveSalaryTime", &b)
+	response, err := c.postJSON(ctx, "/Time/TimesheetSalaryTime/SaveSalaryTime", bytes.NewReader(body))
 	if err != nil {
 		return nil, err
 	}
 
-	body, err := ioutil.ReadAll(response.Body)
-	if err != nil {
-		return nil, fmt.Errorf("error reading response body: %v", err)
-	}
-	err = response.Body.Close()
-	if err != nil {
-		return nil, fmt.Errorf("error closing response body: %v", err)
-	}
-
-	//fmt.Printf("body:\n\n%s\n\n\n", body)
-
 	var saveResponse SaveSalaryTimeResponse
-	err = json.Unmarshal(body, &saveResponse)
-	//err = json.NewDecoder(response.Body).Decode(&saveResponse)
+	err = c.decode(response, &saveResponse)
 	if err != nil {
 		return nil, err
 	}
@@ -146,6 +142,11 @@ func (c *Client) SaveSalaryTime(time EmployeeSalaryTime) (*SaveSalaryTimeRespons
 
 //GetSalaryActivity returns the full details of the SalaryActivity for the employee
 func (c *Client) GetSalaryActivity(employee string, activityID int, from time.Time, to time.Time) (*SalaryTime, error) {
+	return c.GetSalaryActivityContext(context.Background(), employee, activityID, from, to)
+}
+
+//GetSalaryActivityContext is like GetSalaryActivity but with a context
+func (c *Client) GetSalaryActivityContext(ctx context.Context, employee string, activityID int, from time.Time, to time.Time) (*SalaryTime, error) {
 	url := "/Time/TimesheetSalaryTime/GetActivityInformation?activityId=%d&employeeId=%s&fromDate=%s&toDate=%s&_=%s"
 
 	fromString := from.UTC().Format("2006-01-02T15:04:05.000Z")
@@ -153,23 +154,13 @@ func (c *Client) GetSalaryActivity(employee string, activityID int, from time.Ti
 	timestamp := strconv.Itoa(int(time.Now().UnixNano()))
 
 	url = fmt.Sprintf(url, activityID, employee, fromString, toString, timestamp)
-	response, err := c.get(url)
+	response, err := c.get(ctx, url)
 	if err != nil {
 		return nil, err
 	}
 
 	var details SalaryTime
-	body, err := ioutil.ReadAll(response.Body)
-	if err != nil {
-		return nil, fmt.Errorf("error reading response body: %v", err)
-	}
-	err = response.Body.Close()
-	if err != nil {
-		return nil, fmt.Errorf("error closing response body: %v", err)
-	}
-
-	err = json.Unmarshal(body, &details)
-	//err = json.NewDecoder(response.Body).Decode(&details)
+	err = c.decode(response, &details)
 	if err != nil {
 		return nil, err
 	}
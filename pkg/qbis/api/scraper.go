@@ -3,58 +3,78 @@ package api
 import (
 	"fmt"
 	"io"
-	"regexp"
+	"strings"
 
 	"golang.org/x/net/html"
 )
 
-//getCurrentUserFromCurrentLoginBlock parses javascript looking for currentLogin-object
-//and returns the currentUser value (employee ID)
-// TODO improve this to get more than just the employee ID
-func getCurrentUserFromCurrentLoginBlock(script string) (string, error) {
-	regex, err := regexp.Compile(".*var currentLogin = {((?:\n|.)*)};.*")
-	if err != nil {
-		return "", err
-	}
-	bs := regex.FindSubmatch([]byte(script))
-	if len(bs) != 2 {
-		return "", fmt.Errorf("unexpected number of matching groups in javascript block")
-	}
-
-	json := bs[1]
-
-	// currentUser is the employee Id
-	regex, _ = regexp.Compile(`.*currentUser:\s+'(\d+)'.*`)
-	groups := regex.FindSubmatch(json)
-
-	if len(groups) != 2 {
-		return "", fmt.Errorf("unexpected number of matching groups in javascript block")
-	}
-	return string(groups[1]), nil
-
-	/*
-		// The following code was an attempted to convert the javascript to JSON to be unmarshalled - still want to get this working to easier get access to the other fields
-		json = []byte("{" + string(json) + "}")
+//getLoginErrorMessage returns the text of the error and validation elements on the login page
+func getLoginErrorMessage(r io.Reader) string {
+	messages := make([]string, 0)
 
-		regex, _ = regexp.Compile(`(,|{)([a-zA-Z0-9]+?):(.+?)\n`)
-		json = regex.ReplaceAllFunc(json, func(match []byte) []byte {
-			r, _ := regexp.Compile(`(,|{)([a-zA-Z0-9]+?):(.+?)\n`)
-			groups := r.FindSubmatch(match)
-			// TODO guard for index out of bound errors
-			if len(groups) == 0 {
-				return match
+	htmlTokenizer := html.NewTokenizer(r)
+	depth := 0 // > 0 while inside an error element
+lbreak:
+	for {
+		tt := htmlTokenizer.Next()
+		switch tt {
+		case html.ErrorToken:
+			break lbreak
+		case html.TextToken:
+			if depth > 0 {
+				text := strings.TrimSpace(string(htmlTokenizer.Text()))
+				if text != "" {
+					messages = append(messages, text)
+				}
+			}
+		case html.StartTagToken:
+			tn, hasAttr := htmlTokenizer.TagName()
+			if isVoidElement(string(tn)) {
+				continue
+			}
+			if depth > 0 {
+				depth++
+				continue
+			}
+			for hasAttr {
+				var key, val []byte
+				key, val, hasAttr = htmlTokenizer.TagAttr()
+				if string(key) == "class" && isErrorClass(string(val)) {
+					depth = 1
+					break
+				}
 			}
-			leadingSeparator := string(groups[1])
-			key := string(groups[2])
-			quotedValue := string(groups[3])
+		case html.EndTagToken:
+			if depth > 0 {
+				depth--
+			}
+		}
+	}
 
-			quotedValue = strings.TrimSpace(quotedValue)
-			Value := strings.Trim(quotedValue, `'`)
+	return strings.Join(messages, " ")
+}
 
-			return []byte(leadingSeparator + `"` + key + `": "` + Value + `"`)
-		})
-	*/
+//isErrorClass returns true if the class attribute looks like it belongs to an error message
+func isErrorClass(class string) bool {
+	for _, c := range strings.Fields(strings.ToLower(class)) {
+		if c == "has-error" {
+			// bootstrap marks the whole form group, including the label
+			continue
+		}
+		if strings.Contains(c, "error") || c == "alert-danger" {
+			return true
+		}
+	}
+	return false
+}
 
+//isVoidElement returns true for html elements that never have an end tag
+func isVoidElement(tag string) bool {
+	switch tag {
+	case "area", "base", "br", "col", "embed", "hr", "img", "input", "link", "meta", "source", "track", "wbr":
+		return true
+	}
+	return false
 }
 
 //getEmbeddedScriptsInHTML returns the content of all script tags in the reader
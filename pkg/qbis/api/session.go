@@ -0,0 +1,134 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+)
+
+//ErrNoSession is returned by SessionStore.LoadSession when there is no stored session
+var ErrNoSession = errors.New("no stored session")
+
+//Session contains the cookies of a logged in Qbis session (SessionID and the remember me cookie),
+//and the company and user that logged in
+type Session struct {
+	URL     string         `json:"url"`
+	Company string         `json:"company,omitempty"`
+	User    string         `json:"user,omitempty"`
+	Cookies []*http.Cookie `json:"cookies"`
+}
+
+//SessionStore persists Qbis sessions between runs so we don't have to log in every time
+type SessionStore interface {
+	//LoadSession returns the stored session, or ErrNoSession if there is none
+	LoadSession() (*Session, error)
+	//SaveSession stores the session, replacing any previously stored session
+	SaveSession(session *Session) error
+}
+
+//FileSessionStore is a SessionStore that keeps the session as json in a file
+type FileSessionStore struct {
+	path string
+}
+
+//NewFileSessionStore returns a FileSessionStore that keeps the session in the file at path
+func NewFileSessionStore(path string) *FileSessionStore {
+	return &FileSessionStore{path: path}
+}
+
+//LoadSession reads the session from the file
+func (s *FileSessionStore) LoadSession() (*Session, error) {
+	data, err := ioutil.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return nil, ErrNoSession
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to read session file: %v", err)
+	}
+
+	var session Session
+	err = json.Unmarshal(data, &session)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse session file: %v", err)
+	}
+	return &session, nil
+}
+
+//SaveSession writes the session to the file. The file is only readable by the current user
+//since the cookies are as good as a password.
+func (s *FileSessionStore) SaveSession(session *Session) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+
+	// write to a temporary file first so we never leave a half written session behind
+	tmp, err := ioutil.TempFile(filepath.Dir(s.path), filepath.Base(s.path)+".tmp")
+	if err != nil {
+		return fmt.Errorf("unable to create session file: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if err = tmp.Chmod(0600); err == nil {
+		_, err = tmp.Write(data)
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("unable to write session file: %v", err)
+	}
+
+	return os.Rename(tmp.Name(), s.path)
+}
+
+//Session returns the current session of the client so that it can be stored and restored later
+func (c *Client) Session() (*Session, error) {
+	u, err := url.Parse(c.url)
+	if err != nil {
+		return nil, err
+	}
+	session := &Session{
+		URL:     c.url,
+		Cookies: c.client.Jar.Cookies(u),
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.credentials != nil {
+		session.Company = c.credentials.company
+		session.User = c.credentials.user
+	}
+	return session, nil
+}
+
+//RestoreSession loads the cookies of a stored session into the client.
+//Use GetUserEmployeeID to check if the session is still valid.
+func (c *Client) RestoreSession(session *Session) error {
+	if session == nil {
+		return ErrNoSession
+	}
+	u, err := url.Parse(c.url)
+	if err != nil {
+		return err
+	}
+	c.client.Jar.SetCookies(u, session.Cookies)
+	return nil
+}
+
+//RestoreSessionWithCredentials is like RestoreSession, but also keeps the credentials
+//so that the client can log in again when the restored session expires
+func (c *Client) RestoreSessionWithCredentials(session *Session, company string, user string, password string) error {
+	err := c.RestoreSession(session)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.credentials = &credentials{company, user, password}
+	return nil
+}
@@ -143,25 +143,25 @@ type TimesheetDataOld struct {
 type ScheduleDay struct {
 	Activities               []interface{} `json:"Activities"`
 	Arrive                   string        `json:"Arrive"`
-	DayDate                  string        `json:"DayDate"`
+	DayDate                  Date          `json:"DayDate"`
 	HasScheduleArriveOrLeave bool          `json:"HasScheduleArriveOrLeave"`
 	Leave                    string        `json:"Leave"`
 	LunchFrom                string        `json:"LunchFrom"`
-	LunchMinutes             int           `json:"LunchMinutes"`
+	LunchMinutes             Minutes       `json:"LunchMinutes"`
 	LunchTo                  string        `json:"LunchTo"`
 	OverridePublicHolidays   bool          `json:"OverridePublicHolidays"`
 	PrefillTime              bool          `json:"PrefillTime"`
 	ScheduleEmployeeFrom     string        `json:"ScheduleEmployeeFrom"`
 	ScheduleEmployeeTo       string        `json:"ScheduleEmployeeTo"`
 	SkipDeviationValidation  bool          `json:"SkipDeviationValidation"`
-	TotalMinutes             int           `json:"TotalMinutes"`
+	TotalMinutes             Minutes       `json:"TotalMinutes"`
 	Tracking                 int           `json:"Tracking"`
 }
 
 //DaySetting is included in TimesheetData for every day of the week
 type DaySetting struct {
 	DayComment                            interface{} `json:"DayComment"`
-	DayDate                               string      `json:"DayDate"`
+	DayDate                               Date        `json:"DayDate"`
 	DayNameString                         string      `json:"DayNameString"`
 	DisabledTooltipProjectTime            string      `json:"DisabledTooltipProjectTime"`
 	DisabledTooltipSalaryTime             string      `json:"DisabledTooltipSalaryTime"`
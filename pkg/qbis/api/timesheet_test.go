@@ -0,0 +1,133 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+)
+
+func readTimesheet(t *testing.T) *TimesheetData {
+	t.Helper()
+	recorded, err := ioutil.ReadFile("testdata/timesheet.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sheet TimesheetData
+	err = json.Unmarshal(recorded, &sheet)
+	if err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	return &sheet
+}
+
+func TestTimesheetDataRoundTrip(t *testing.T) {
+	sheet := readTimesheet(t)
+	encoded, err := json.Marshal(sheet)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	if bytes.Contains(encoded, []byte("/Date(")) {
+		t.Errorf("re-encoded timesheet has qbis dates, want ISO 8601:\n%s", encoded)
+	}
+
+	// reading what was written gives the same timesheet
+	var again TimesheetData
+	err = json.Unmarshal(encoded, &again)
+	if err != nil {
+		t.Fatalf("Unmarshal re-encoded timesheet: %v", err)
+	}
+	reencoded, err := json.Marshal(again)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	if !bytes.Equal(encoded, reencoded) {
+		t.Errorf("timesheet changed when read again:\n%s\n%s", encoded, reencoded)
+	}
+	for i, ds := range sheet.DaySettings {
+		if !ds.DayDate.Equal(again.DaySettings[i].DayDate.Time) {
+			t.Errorf("day %d is %v, want %v", i, again.DaySettings[i].DayDate.Time, ds.DayDate.Time)
+		}
+	}
+	project := again.ListOfProjectTime[0]
+	if !project.StartDate.IsZero() || !strings.Contains(string(reencoded), `"StartDate":""`) {
+		t.Errorf("empty start date was not kept: %v", project.StartDate.Time)
+	}
+}
+
+func TestSavePayloadsHaveISODates(t *testing.T) {
+	sheet := readTimesheet(t)
+	days := make([]WorkingTimeBase, 0)
+	for _, wt := range sheet.WorkingTimeDays {
+		days = append(days, wt.WorkingTimeBase)
+	}
+	salary := make([]SalaryTimeBase, 0)
+	for _, st := range sheet.ListOfSalaryTime {
+		salary = append(salary, st.SalaryTimeBase)
+	}
+	payloads := []interface{}{
+		EmployeeWorkingTime{Days: days},
+		EmployeeSalaryTime{SalaryTime: salary, WorkingTime: days},
+		EmployeeProjectTime{List: sheet.ListOfProjectTime},
+	}
+	for _, payload := range payloads {
+		body, err := EncodePayload(payload)
+		if err != nil {
+			t.Fatalf("EncodePayload(%T): %v", payload, err)
+		}
+		if bytes.Contains(body, []byte("/Date(")) {
+			t.Errorf("%T has qbis dates, want ISO 8601:\n%s", payload, body)
+		}
+		if !bytes.Contains(body, []byte(`"DayDate":"2024-03-03T23:00:00.000Z"`)) {
+			t.Errorf("%T does not have monday as an ISO 8601 date:\n%s", payload, body)
+		}
+	}
+	body, err := EncodePayload(EmployeeWorkingTime{Days: days})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(body, []byte(`"BreakDate":"2024-03-03T23:00:00.000Z"`)) {
+		t.Errorf("break date is not ISO 8601:\n%s", body)
+	}
+}
+
+func TestDateMarshalJSON(t *testing.T) {
+	monday := time.Date(2024, time.March, 4, 0, 0, 0, 0, time.UTC)
+	read := func(data string) Date {
+		var d Date
+		err := json.Unmarshal([]byte(data), &d)
+		if err != nil {
+			t.Fatalf("Unmarshal(%s): %v", data, err)
+		}
+		return d
+	}
+	changed := read(`"/Date(1709510400000)/"`)
+	changed.Time = changed.AddDate(0, 0, 1)
+
+	tests := []struct {
+		name string
+		date Date
+		want string
+	}{
+		{"new", NewDate(monday), `"2024-03-04T00:00:00.000Z"`},
+		{"new zero", Date{}, `null`},
+		{"read", read(`"/Date(1709510400000)/"`), `"2024-03-04T00:00:00.000Z"`},
+		{"read escaped", read(`"\/Date(1709510400000+0100)\/"`), `"2024-03-04T00:00:00.000Z"`},
+		{"read iso", read(`"2024-03-04T00:00:00Z"`), `"2024-03-04T00:00:00.000Z"`},
+		{"read empty", read(`""`), `""`},
+		{"read null", read(`null`), `null`},
+		{"changed", changed, `"2024-03-05T00:00:00.000Z"`},
+	}
+	for _, test := range tests {
+		got, err := json.Marshal(test.date)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if string(got) != test.want {
+			t.Errorf("%s: got %s, want %s", test.name, got, test.want)
+		}
+	}
+}
@@ -2,7 +2,7 @@ package api
 
 import (
 	"bytes"
-	"encoding/json"
+	"context"
 )
 
 //WorkingTime ...
@@ -13,46 +13,46 @@ type WorkingTime struct {
 
 //WorkingTimeBase the base working time struct is embedded in another struct in TimesheetData. Also used as input to save data
 type WorkingTimeBase struct {
-	Arrive int `json:"Arrive"`
+	Arrive Minutes `json:"Arrive"`
 	Breaks []struct {
-		BreakDate        string `json:"BreakDate"`
-		BreakFromMinutes int    `json:"BreakFromMinutes"`
-		BreakID          int    `json:"BreakId"`
-		BreakToMinutes   int    `json:"BreakToMinutes"`
-		EmployeeID       int    `json:"EmployeeId"`
-		Source           int    `json:"Source"`
+		BreakDate        Date    `json:"BreakDate"`
+		BreakFromMinutes Minutes `json:"BreakFromMinutes"`
+		BreakID          int     `json:"BreakId"`
+		BreakToMinutes   Minutes `json:"BreakToMinutes"`
+		EmployeeID       int     `json:"EmployeeId"`
+		Source           int     `json:"Source"`
 	} `json:"Breaks"`
-	DayDate                    string `json:"DayDate"`
-	DayName                    string `json:"DayName"`
-	HasSchedule                bool   `json:"HasSchedule"`
-	ID                         int    `json:"ID"`
-	IsModified                 bool   `json:"IsModified"`
-	IsMonthClosed              bool   `json:"IsMonthClosed"`
-	IsOutsideJoinAndLeaveDates bool   `json:"IsOutsideJoinAndLeaveDates"`
-	IsPublicHoliday            bool   `json:"IsPublicHoliday"`
-	IsSaved                    bool   `json:"IsSaved"`
-	IsScheduleHourOnly         bool   `json:"IsScheduleHourOnly"`
-	IsToday                    bool   `json:"IsToday"`
-	Leave                      int    `json:"Leave"`
-	Locked                     bool   `json:"Locked"`
-	Lunch                      int    `json:"Lunch"`
-	NextDay                    string `json:"NextDay"`
-	Overmidnight               bool   `json:"Overmidnight"`
-	OverridePublicHolidays     bool   `json:"OverridePublicHolidays"`
-	PrefillSpecifiedBreak      bool   `json:"PrefillSpecifiedBreak"`
-	ScheduledHours             int    `json:"ScheduledHours"`
-	Total                      int    `json:"Total"`
+	DayDate                    Date    `json:"DayDate"`
+	DayName                    string  `json:"DayName"`
+	HasSchedule                bool    `json:"HasSchedule"`
+	ID                         int     `json:"ID"`
+	IsModified                 bool    `json:"IsModified"`
+	IsMonthClosed              bool    `json:"IsMonthClosed"`
+	IsOutsideJoinAndLeaveDates bool    `json:"IsOutsideJoinAndLeaveDates"`
+	IsPublicHoliday            bool    `json:"IsPublicHoliday"`
+	IsSaved                    bool    `json:"IsSaved"`
+	IsScheduleHourOnly         bool    `json:"IsScheduleHourOnly"`
+	IsToday                    bool    `json:"IsToday"`
+	Leave                      Minutes `json:"Leave"`
+	Locked                     bool    `json:"Locked"`
+	Lunch                      Minutes `json:"Lunch"`
+	NextDay                    string  `json:"NextDay"`
+	Overmidnight               bool    `json:"Overmidnight"`
+	OverridePublicHolidays     bool    `json:"OverridePublicHolidays"`
+	PrefillSpecifiedBreak      bool    `json:"PrefillSpecifiedBreak"`
+	ScheduledHours             int     `json:"ScheduledHours"`
+	Total                      Minutes `json:"Total"`
 }
 
 // WorkingTimeBreak is read in timesheet and used in Save salary time
 type WorkingTimeBreak struct {
 	Days []struct {
-		BreakDate        string `json:"BreakDate"`
-		BreakFromMinutes int    `json:"BreakFromMinutes"`
-		BreakID          int    `json:"BreakId"`
-		BreakToMinutes   int    `json:"BreakToMinutes"`
-		EmployeeID       int    `json:"EmployeeId"`
-		Source           int    `json:"Source"`
+		BreakDate        Date    `json:"BreakDate"`
+		BreakFromMinutes Minutes `json:"BreakFromMinutes"`
+		BreakID          int     `json:"BreakId"`
+		BreakToMinutes   Minutes `json:"BreakToMinutes"`
+		EmployeeID       int     `json:"EmployeeId"`
+		Source           int     `json:"Source"`
 	} `json:"Days"`
 	IsNewRow bool `json:"IsNewRow"`
 }
@@ -61,8 +61,8 @@ type WorkingTimeBreak struct {
 type EmployeeWorkingTime struct {
 	Days       []WorkingTimeBase `json:"days"`
 	EmployeeID string            `json:"employeeId"`
-	FromDate   string            `json:"fromDate"`
-	ToDate     string            `json:"toDate"`
+	FromDate   Date              `json:"fromDate"`
+	ToDate     Date              `json:"toDate"`
 }
 
 //SaveWorkingTimeResponse ...
@@ -72,18 +72,22 @@ type SaveWorkingTimeResponse struct {
 
 //SaveWorkingTime saves the matrix with hours spent working
 func (c *Client) SaveWorkingTime(time EmployeeWorkingTime) (*SaveWorkingTimeResponse, error) {
+	return c.SaveWorkingTimeContext(context.Background(), time)
+}
+
+//SaveWorkingTimeContext is like SaveWorkingTime but with a context
+func (c *Client) SaveWorkingTimeContext(ctx context.Context, time EmployeeWorkingTime) (*SaveWorkingTimeResponse, error) {
 	// https://login.qbis.se/Time/TimesheetWorkingTime/SaveWorkingTime
-	var b bytes.Buffer
-	err := json.NewEncoder(&b).Encode(time)
+	body, err := EncodePayload(time)
 	if err != nil {
 		return nil, err
 	}
-	response, err := c.postJSON("/Time/TimesheetWorkingTime/SaveWorkingTime", &b)
+	response, err := c.postJSON(ctx, "/Time/TimesheetWorkingTime/SaveWorkingTime", bytes.NewReader(body))
 	if err != nil {
 		return nil, err
 	}
 	var saveResponse SaveWorkingTimeResponse
-	err = json.NewDecoder(response.Body).Decode(&saveResponse)
+	err = c.decode(response, &saveResponse)
 	if err != nil {
 		return nil, err
 	}
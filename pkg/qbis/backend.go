@@ -0,0 +1,26 @@
+package qbis
+
+import (
+	"context"
+	"time"
+
+	"github.com/flipb/qbis-time/pkg/qbis/api"
+)
+
+//API is the part of the low level api the high level client needs.
+//It is implemented by *api.Client, and by qbistest.MemoryAPI for tests that should not touch the network.
+type API interface {
+	GetLoginInfoContext(ctx context.Context) (*api.LoginInfo, error)
+	GetTimesheetContext(ctx context.Context, employee string, from time.Time, to time.Time) (*api.TimesheetData, error)
+
+	GetProjectsContext(ctx context.Context, employee string, from time.Time, to time.Time) ([]api.ProjectCompany, error)
+	GetProjectActivityListContext(ctx context.Context, employee string, projectID int, from time.Time, to time.Time) ([]api.ProjectActivityListItem, error)
+	GetProjectActivityContext(ctx context.Context, employee string, activityID int, from time.Time, to time.Time) (*api.ProjectTime, error)
+	GetSalaryActivityContext(ctx context.Context, employee string, activityID int, from time.Time, to time.Time) (*api.SalaryTime, error)
+
+	SaveSalaryTimeContext(ctx context.Context, time api.EmployeeSalaryTime) (*api.SaveSalaryTimeResponse, error)
+	SaveWorkingTimeContext(ctx context.Context, time api.EmployeeWorkingTime) (*api.SaveWorkingTimeResponse, error)
+	SaveProjectTimeContext(ctx context.Context, time api.EmployeeProjectTime) (*api.SaveProjectTimeResponse, error)
+}
+
+var _ API = (*api.Client)(nil)
@@ -3,45 +3,120 @@
 package qbis
 
 import (
+	"context"
 	"fmt"
 	"time"
 
 	"github.com/flipb/qbis-time/pkg/qbis/api"
 )
 
+//defaultURL is the address of the Qbis service
+const defaultURL = "https://login.qbis.se"
+
 //Client is a highlevel qbis client
 type Client struct {
-	apiClient  api.Client
+	apiClient  API
 	employeeID string
+	loginInfo  *api.LoginInfo
+
+	location  *time.Location
+	logger    api.Logger
+	validator *Validator
+}
+
+//NewClient creates a new qbis client and logs in. Use options to change the defaults
+func NewClient(qbisCompany string, qbisUser string, qbisPassword string, opts ...Option) (*Client, error) {
+	return NewClientContext(context.Background(), qbisCompany, qbisUser, qbisPassword, opts...)
 }
 
-//NewClient creates a new qbis client
-func NewClient(qbisCompany string, qbisUser string, qbisPassword string) (*Client, error) {
-	qbisClient := api.New("https://login.qbis.se")
+//NewClientContext is like NewClient but with a context
+func NewClientContext(ctx context.Context, qbisCompany string, qbisUser string, qbisPassword string, opts ...Option) (*Client, error) {
+	o := newOptions(opts)
+
+	if o.store != nil {
+		client, err := newClientFromSession(ctx, o, &login{qbisCompany, qbisUser, qbisPassword})
+		if err == nil {
+			return client, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		// any other problem with the stored session just means we have to log in again
+	}
+
+	qbisClient := o.apiClient(o.url)
 
-	err := qbisClient.Login(qbisCompany, qbisUser, qbisPassword)
+	err := qbisClient.LoginContext(ctx, qbisCompany, qbisUser, qbisPassword)
 	if err != nil {
-		return nil, fmt.Errorf("login to qbis failed: %v", err)
+		return nil, fmt.Errorf("login to qbis failed: %w", err)
+	}
+
+	client, err := newClient(ctx, qbisClient, o)
+	if err != nil {
+		return nil, err
+	}
+
+	if o.store != nil {
+		err = saveSession(qbisClient, o.store)
+		if err != nil {
+			return nil, err
+		}
 	}
+	return client, nil
+}
+
+//NewClientFromAPIClient creates a new qbis client using an *api.Client or any other implementation of API.
+//Options that configure the connection (url, http client, language, session store) are ignored.
+func NewClientFromAPIClient(client API, opts ...Option) (*Client, error) {
+	return NewClientFromAPIClientContext(context.Background(), client, opts...)
+}
 
-	return NewClientFromAPIClient(*qbisClient)
+//NewClientFromAPIClientContext is like NewClientFromAPIClient but with a context
+func NewClientFromAPIClientContext(ctx context.Context, client API, opts ...Option) (*Client, error) {
+	return newClient(ctx, client, newOptions(opts))
 }
 
-//NewClientFromAPIClient creates a new qbis client
-func NewClientFromAPIClient(client api.Client) (*Client, error) {
+func newClient(ctx context.Context, client API, o *options) (*Client, error) {
 
-	employee, err := client.GetUserEmployeeID()
+	info, err := client.GetLoginInfoContext(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	return &Client{client, employee}, nil
+	logger := o.logger
+	if logger == nil {
+		logger = nopLogger{}
+	}
+
+	return &Client{
+		apiClient:  client,
+		employeeID: info.EmployeeID,
+		loginInfo:  info,
+		location:   o.location,
+		logger:     logger,
+		validator:  o.validator(),
+	}, nil
+}
+
+//LoginInfo returns the profile of the logged in user, as it was when the client was created
+func (q Client) LoginInfo() api.LoginInfo {
+	return *q.loginInfo
 }
 
+//nopLogger discards everything
+type nopLogger struct{}
+
+func (nopLogger) Printf(format string, v ...interface{}) {}
+
 //Week returns the week containing a specific point in time
 func (q Client) Week(time time.Time) (*Week, error) {
+	return q.WeekContext(context.Background(), time)
+}
+
+//WeekContext is like Week but with a context
+func (q Client) WeekContext(ctx context.Context, time time.Time) (*Week, error) {
 	w := Week{}
-	s, e, err := getWeekSpan(time)
+	s, e, err := getWeekSpan(time, q.location)
 	if err != nil {
 		return nil, err
 	}
@@ -49,7 +124,7 @@ func (q Client) Week(time time.Time) (*Week, error) {
 	w.end = e
 	w.client = q
 
-	err = w.Update()
+	err = w.UpdateContext(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -61,3 +136,8 @@ func (q Client) Week(time time.Time) (*Week, error) {
 func (q Client) WeekNow() (*Week, error) {
 	return q.Week(time.Now())
 }
+
+//WeekNowContext is like WeekNow but with a context
+func (q Client) WeekNowContext(ctx context.Context) (*Week, error) {
+	return q.WeekContext(ctx, time.Now())
+}
@@ -0,0 +1,74 @@
+package qbis_test
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/flipb/qbis-time/pkg/qbis"
+	"github.com/flipb/qbis-time/pkg/qbis/qbistest"
+)
+
+func TestClientWithMemoryAPI(t *testing.T) {
+	store := qbistest.NewStore()
+	c, err := qbis.NewClientFromAPIClient(qbistest.NewMemoryAPI(store))
+	if err != nil {
+		t.Fatalf("NewClientFromAPIClient: %v", err)
+	}
+	if got := c.LoginInfo().EmployeeID; got != strconv.Itoa(qbistest.DefaultEmployeeID) {
+		t.Fatalf("employee ID = %q, want %d", got, qbistest.DefaultEmployeeID)
+	}
+
+	wednesday := time.Date(2024, time.March, 6, 12, 0, 0, 0, time.Local)
+	w, err := c.Week(wednesday)
+	if err != nil {
+		t.Fatalf("Week: %v", err)
+	}
+	d, err := w.Weekday(time.Wednesday)
+	if err != nil {
+		t.Fatalf("Weekday: %v", err)
+	}
+	if err := d.SetWorkingHours(8*60, 17*60); err != nil {
+		t.Fatalf("SetWorkingHours: %v", err)
+	}
+	d.SetBreakMinutes(60)
+	if err := d.SetProjectTime(qbistest.DevelopmentActivityID, 240); err != nil {
+		t.Fatalf("SetProjectTime: %v", err)
+	}
+	if err := d.SetSalaryTime(qbistest.SickLeaveActivityID, -60); err != nil {
+		t.Fatalf("SetSalaryTime: %v", err)
+	}
+
+	result, err := w.Save()
+	if err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	for _, s := range result.Sections {
+		if !s.Saved {
+			t.Errorf("%v was not saved: %v", s.Section, s.Err)
+		}
+	}
+
+	// a new week reads what was stored
+	w, err = c.Week(wednesday)
+	if err != nil {
+		t.Fatalf("Week: %v", err)
+	}
+	d, err = w.Weekday(time.Wednesday)
+	if err != nil {
+		t.Fatalf("Weekday: %v", err)
+	}
+	logged, err := d.LoggedMinutes()
+	if err != nil || logged != 480 {
+		t.Errorf("logged minutes = %d, %v, want 480", logged, err)
+	}
+	if got := d.ProjectTimeMinutes(qbistest.DevelopmentActivityID); got != 240 {
+		t.Errorf("project time = %d, want 240", got)
+	}
+	if got := d.SalaryTimeMinutes(qbistest.SickLeaveActivityID); got != -60 {
+		t.Errorf("salary time = %d, want -60", got)
+	}
+	if changes := w.Diff(); len(changes) != 0 {
+		t.Errorf("fresh week has changes: %v", changes)
+	}
+}
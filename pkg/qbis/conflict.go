@@ -0,0 +1,374 @@
+package qbis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/flipb/qbis-time/pkg/qbis/api"
+)
+
+//Cell identifies one value in the timesheet of a week
+type Cell struct {
+	Section  Section
+	Activity int    // activity ID, 0 for working time
+	Date     string // the day, formatted as 2006-01-02
+	Field    string // name of the field in the api, eg. "Arrive" or "InternalNotes"
+}
+
+func (c Cell) String() string {
+	if c.Section == SectionWorkingTime {
+		return fmt.Sprintf("%v %s %s", c.Section, c.Date, c.Field)
+	}
+	return fmt.Sprintf("%v activity %d %s %s", c.Section, c.Activity, c.Date, c.Field)
+}
+
+//cells holds the values of a timesheet by cell. Empty values and zero minutes are left out,
+//so a row that is missing from one sheet compares equal to an empty row in another.
+type cells map[Cell]string
+
+//sortedCells returns the cells of the set in a stable order
+func sortedCells(set map[Cell]bool) []Cell {
+	sorted := make([]Cell, 0, len(set))
+	for c := range set {
+		sorted = append(sorted, c)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		if a.Section != b.Section {
+			return a.Section < b.Section
+		}
+		if a.Activity != b.Activity {
+			return a.Activity < b.Activity
+		}
+		if a.Date != b.Date {
+			return a.Date < b.Date
+		}
+		return a.Field < b.Field
+	})
+	return sorted
+}
+
+//flatten returns the values of the sheet that are sent when saving
+func (w *Week) flatten(sheet *api.TimesheetData) cells {
+	c := make(cells)
+	set := func(section Section, activity int, day int, field string, value string) {
+		if value == "" || value == "0" {
+			return
+		}
+		c[Cell{Section: section, Activity: activity, Date: w.dayKey(day), Field: field}] = value
+	}
+	minutes := func(m api.Minutes) string {
+		return strconv.Itoa(int(m))
+	}
+
+	for i, day := range sheet.WorkingTimeDays {
+		set(SectionWorkingTime, 0, i, "Arrive", minutes(day.Arrive))
+		set(SectionWorkingTime, 0, i, "Leave", minutes(day.Leave))
+		set(SectionWorkingTime, 0, i, "Lunch", minutes(day.Lunch))
+	}
+	for _, row := range sheet.ListOfSalaryTime {
+		for i, day := range row.Days {
+			set(SectionSalaryTime, row.ActivityID, i, "DayMinutes", minutes(day.DayMinutes))
+			set(SectionSalaryTime, row.ActivityID, i, "DayDays", strconv.Itoa(day.DayDays))
+			set(SectionSalaryTime, row.ActivityID, i, "Notes", day.Notes)
+		}
+	}
+	for _, row := range sheet.ListOfProjectTime {
+		for i, day := range row.Days {
+			set(SectionProjectTime, row.ActivityID, i, "DayMinutes", minutes(day.DayMinutes))
+			set(SectionProjectTime, row.ActivityID, i, "InternalNotes", day.InternalNotes)
+			set(SectionProjectTime, row.ActivityID, i, "ExternalNotes", day.ExternalNotes)
+		}
+	}
+	return c
+}
+
+//dayKey returns the date of the day with the index in the week
+func (w *Week) dayKey(day int) string {
+	return w.start.AddDate(0, 0, day).Format("2006-01-02")
+}
+
+//dayIndex returns the index in the week of the date formatted by dayKey
+func (w *Week) dayIndex(date string) int {
+	for i := 0; i < 7; i++ {
+		if w.dayKey(i) == date {
+			return i
+		}
+	}
+	return -1
+}
+
+//sentSections returns the sections whose values are sent when saving the sections.
+//The salary time payload includes the working time.
+func sentSections(sections []Section) map[Section]bool {
+	sent := make(map[Section]bool)
+	for _, s := range sections {
+		sent[s] = true
+		if s == SectionSalaryTime {
+			sent[SectionWorkingTime] = true
+		}
+	}
+	return sent
+}
+
+//Conflict is a cell that was changed in Qbis after the week was loaded, and would be overwritten by saving
+type Conflict struct {
+	Cell     Cell
+	Original string // the value when the week was loaded
+	Theirs   string // the value in Qbis now
+	Ours     string // the value that would be saved
+}
+
+func (c Conflict) String() string {
+	return fmt.Sprintf("%v: was %q, is now %q in qbis, saving %q", c.Cell, c.Original, c.Theirs, c.Ours)
+}
+
+//ConflictError is returned by Save when the week was changed in Qbis since it was loaded,
+//in cells that saving would overwrite. Use Merge to apply the changes to the current Qbis data,
+//or save with WithForce to overwrite them.
+type ConflictError struct {
+	Conflicts []Conflict
+}
+
+func (e *ConflictError) Error() string {
+	conflicts := make([]string, 0, len(e.Conflicts))
+	for _, c := range e.Conflicts {
+		conflicts = append(conflicts, c.String())
+	}
+	return fmt.Sprintf("week was changed in qbis since it was loaded: %s", strings.Join(conflicts, "; "))
+}
+
+//conflicts returns the cells of the sections that differ between the snapshot taken when the week was loaded
+//and the fresh sheet, unless we changed them to the same value
+func (w *Week) conflicts(fresh *api.TimesheetData, sections []Section) []Conflict {
+	original := w.flatten(w.original)
+	theirs := w.flatten(fresh)
+	ours := w.flatten(w.sheet)
+
+	sent := sentSections(sections)
+	all := make(map[Cell]bool)
+	for _, set := range []cells{original, theirs, ours} {
+		for c := range set {
+			if sent[c.Section] {
+				all[c] = true
+			}
+		}
+	}
+
+	conflicts := make([]Conflict, 0)
+	for _, c := range sortedCells(all) {
+		if theirs[c] == original[c] || theirs[c] == ours[c] {
+			continue
+		}
+		conflicts = append(conflicts, Conflict{Cell: c, Original: original[c], Theirs: theirs[c], Ours: ours[c]})
+	}
+	return conflicts
+}
+
+//checkConflicts fetches the week from Qbis and returns a ConflictError if saving the sections
+//would overwrite changes made since the week was loaded
+func (w *Week) checkConflicts(ctx context.Context, sections []Section) error {
+	if w.original == nil {
+		return nil
+	}
+	fresh, err := w.client.apiClient.GetTimesheetContext(ctx, w.client.employeeID, w.start, w.end)
+	if err != nil {
+		return fmt.Errorf("unable to check the week for conflicts: %w", err)
+	}
+	conflicts := w.conflicts(fresh, sections)
+	if len(conflicts) > 0 {
+		return &ConflictError{Conflicts: conflicts}
+	}
+	return nil
+}
+
+//Merge fetches the week from Qbis and applies the changes made since it was loaded on top of it,
+//so that Save only overwrites the cells that were changed here. Where the same cell was changed in both places
+//the change made here wins.
+func (w *Week) Merge() error {
+	return w.MergeContext(context.Background())
+}
+
+//MergeContext is like Merge but with a context
+func (w *Week) MergeContext(ctx context.Context) error {
+	fresh, err := w.client.apiClient.GetTimesheetContext(ctx, w.client.employeeID, w.start, w.end)
+	if err != nil {
+		return err
+	}
+
+	original := w.flatten(w.original)
+	ours := w.flatten(w.sheet)
+	edited := make(map[Cell]bool)
+	for _, set := range []cells{original, ours} {
+		for c := range set {
+			if original[c] != ours[c] {
+				edited[c] = true
+			}
+		}
+	}
+
+	snapshot, err := cloneSheet(fresh)
+	if err != nil {
+		return err
+	}
+	for _, c := range sortedCells(edited) {
+		err = w.setCell(fresh, c, ours[c])
+		if err != nil {
+			return fmt.Errorf("unable to merge %v: %v", c, err)
+		}
+	}
+
+	w.sheet = fresh
+	w.original = snapshot
+	return nil
+}
+
+//setCell sets the value of the cell in the sheet, copying the activity row from the week if the sheet does not have it
+func (w *Week) setCell(sheet *api.TimesheetData, c Cell, value string) error {
+	day := w.dayIndex(c.Date)
+	if day < 0 {
+		return fmt.Errorf("date not in week")
+	}
+	number := func() (int, error) {
+		if value == "" {
+			return 0, nil
+		}
+		return strconv.Atoi(value)
+	}
+
+	switch c.Section {
+	case SectionWorkingTime:
+		if day >= len(sheet.WorkingTimeDays) {
+			return fmt.Errorf("day not in timesheet")
+		}
+		n, err := number()
+		if err != nil {
+			return err
+		}
+		wt := &sheet.WorkingTimeDays[day]
+		switch c.Field {
+		case "Arrive":
+			wt.Arrive = api.Minutes(n)
+		case "Leave":
+			wt.Leave = api.Minutes(n)
+		case "Lunch":
+			wt.Lunch = api.Minutes(n)
+		default:
+			return fmt.Errorf("unknown field")
+		}
+		wt.IsModified = true
+
+	case SectionSalaryTime:
+		row := salaryRow(sheet, c.Activity)
+		if row == nil {
+			ours := salaryRow(w.sheet, c.Activity)
+			if ours == nil {
+				return fmt.Errorf("unknown activity")
+			}
+			var copied api.SalaryTime
+			if err := cloneJSON(ours, &copied); err != nil {
+				return err
+			}
+			sheet.ListOfSalaryTime = append(sheet.ListOfSalaryTime, copied)
+			row = &sheet.ListOfSalaryTime[len(sheet.ListOfSalaryTime)-1]
+		}
+		if day >= len(row.Days) {
+			return fmt.Errorf("day not in activity")
+		}
+		switch c.Field {
+		case "DayMinutes":
+			n, err := number()
+			if err != nil {
+				return err
+			}
+			row.Days[day].DayMinutes = api.Minutes(n)
+		case "DayDays":
+			n, err := number()
+			if err != nil {
+				return err
+			}
+			row.Days[day].DayDays = n
+		case "Notes":
+			row.Days[day].Notes = value
+		default:
+			return fmt.Errorf("unknown field")
+		}
+
+	case SectionProjectTime:
+		row := projectRow(sheet, c.Activity)
+		if row == nil {
+			ours := projectRow(w.sheet, c.Activity)
+			if ours == nil {
+				return fmt.Errorf("unknown activity")
+			}
+			var copied api.ProjectTime
+			if err := cloneJSON(ours, &copied); err != nil {
+				return err
+			}
+			sheet.ListOfProjectTime = append(sheet.ListOfProjectTime, copied)
+			row = &sheet.ListOfProjectTime[len(sheet.ListOfProjectTime)-1]
+		}
+		if day >= len(row.Days) {
+			return fmt.Errorf("day not in activity")
+		}
+		switch c.Field {
+		case "DayMinutes":
+			n, err := number()
+			if err != nil {
+				return err
+			}
+			row.Days[day].DayMinutes = api.Minutes(n)
+		case "InternalNotes":
+			row.Days[day].InternalNotes = value
+		case "ExternalNotes":
+			row.Days[day].ExternalNotes = value
+		default:
+			return fmt.Errorf("unknown field")
+		}
+
+	default:
+		return fmt.Errorf("unknown section")
+	}
+	return nil
+}
+
+func salaryRow(sheet *api.TimesheetData, activityID int) *api.SalaryTime {
+	for i := range sheet.ListOfSalaryTime {
+		if sheet.ListOfSalaryTime[i].ActivityID == activityID {
+			return &sheet.ListOfSalaryTime[i]
+		}
+	}
+	return nil
+}
+
+func projectRow(sheet *api.TimesheetData, activityID int) *api.ProjectTime {
+	for i := range sheet.ListOfProjectTime {
+		if sheet.ListOfProjectTime[i].ActivityID == activityID {
+			return &sheet.ListOfProjectTime[i]
+		}
+	}
+	return nil
+}
+
+//cloneSheet returns a deep copy of the sheet
+func cloneSheet(sheet *api.TimesheetData) (*api.TimesheetData, error) {
+	var copied api.TimesheetData
+	err := cloneJSON(sheet, &copied)
+	if err != nil {
+		return nil, err
+	}
+	return &copied, nil
+}
+
+//cloneJSON deep copies src into dst by way of json
+func cloneJSON(src interface{}, dst interface{}) error {
+	data, err := json.Marshal(src)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, dst)
+}
@@ -0,0 +1,190 @@
+package qbis_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/flipb/qbis-time/pkg/qbis"
+	"github.com/flipb/qbis-time/pkg/qbis/api"
+	"github.com/flipb/qbis-time/pkg/qbis/qbistest"
+)
+
+//monday is the start of the week used by the tests
+var monday = time.Date(2024, time.March, 4, 0, 0, 0, 0, time.Local)
+
+//newWeek returns the week starting at monday, read from the store
+func newWeek(t *testing.T, store *qbistest.Store, opts ...qbis.Option) *qbis.Week {
+	t.Helper()
+	c, err := qbis.NewClientFromAPIClient(qbistest.NewMemoryAPI(store), opts...)
+	if err != nil {
+		t.Fatalf("NewClientFromAPIClient: %v", err)
+	}
+	w, err := c.Week(monday.AddDate(0, 0, 2).Add(12 * time.Hour))
+	if err != nil {
+		t.Fatalf("Week: %v", err)
+	}
+	return w
+}
+
+//setWorkingHours sets the arrival and departure of the day of the week
+func setWorkingHours(t *testing.T, w *qbis.Week, weekday time.Weekday, arrival qbis.ClockTime, departure qbis.ClockTime) {
+	t.Helper()
+	d, err := w.Weekday(weekday)
+	if err != nil {
+		t.Fatalf("Weekday: %v", err)
+	}
+	if err := d.SetWorkingHours(arrival, departure); err != nil {
+		t.Fatalf("SetWorkingHours: %v", err)
+	}
+}
+
+//storedWorkingHours returns the arrival and departure of the day in the store
+func storedWorkingHours(t *testing.T, store *qbistest.Store, day int) (api.Minutes, api.Minutes) {
+	t.Helper()
+	sheet, err := store.Timesheet("1234", monday, monday.AddDate(0, 0, 6))
+	if err != nil {
+		t.Fatalf("Timesheet: %v", err)
+	}
+	wt := sheet.WorkingTimeDays[day]
+	return wt.Arrive, wt.Leave
+}
+
+//editInQbis changes the working hours of the day in the store, as if they were edited in the web ui
+func editInQbis(t *testing.T, store *qbistest.Store, day int, arrival api.Minutes, departure api.Minutes) {
+	t.Helper()
+	err := store.UpdateTimesheet(monday, func(sheet *api.TimesheetData) {
+		sheet.WorkingTimeDays[day].Arrive = arrival
+		sheet.WorkingTimeDays[day].Leave = departure
+	})
+	if err != nil {
+		t.Fatalf("UpdateTimesheet: %v", err)
+	}
+}
+
+func TestSaveConflict(t *testing.T) {
+	store := qbistest.NewStore()
+	w := newWeek(t, store)
+	setWorkingHours(t, w, time.Wednesday, 8*60, 17*60)
+	editInQbis(t, store, 2, 9*60, 16*60)
+
+	_, err := w.Save()
+	var conflict *qbis.ConflictError
+	if !errors.As(err, &conflict) {
+		t.Fatalf("expected a *qbis.ConflictError, got %v", err)
+	}
+	want := []qbis.Conflict{
+		{
+			Cell:     qbis.Cell{Section: qbis.SectionWorkingTime, Date: "2024-03-06", Field: "Arrive"},
+			Original: "", Theirs: "540", Ours: "480",
+		},
+		{
+			Cell:     qbis.Cell{Section: qbis.SectionWorkingTime, Date: "2024-03-06", Field: "Leave"},
+			Original: "", Theirs: "960", Ours: "1020",
+		},
+	}
+	if !reflect.DeepEqual(conflict.Conflicts, want) {
+		t.Errorf("conflicts = %v, want %v", conflict.Conflicts, want)
+	}
+	if arrival, departure := storedWorkingHours(t, store, 2); arrival != 9*60 || departure != 16*60 {
+		t.Errorf("the conflicting save changed qbis to %d-%d", arrival, departure)
+	}
+}
+
+func TestSaveWithoutConflicts(t *testing.T) {
+	tests := []struct {
+		name         string
+		workingHours bool // also set the working hours of wednesday to 08:00-17:00
+		edit         func(sheet *api.TimesheetData)
+	}{
+		{
+			name:         "changed to the same value",
+			workingHours: true,
+			edit: func(sheet *api.TimesheetData) {
+				sheet.WorkingTimeDays[2].Arrive = 8 * 60
+				sheet.WorkingTimeDays[2].Leave = 17 * 60
+			},
+		},
+		{
+			name: "changed in a section that is not saved",
+			edit: func(sheet *api.TimesheetData) {
+				sheet.ListOfSalaryTime[0].Days[0].DayMinutes = 60
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store := qbistest.NewStore()
+			w := newWeek(t, store)
+			d, err := w.Weekday(time.Wednesday)
+			if err != nil {
+				t.Fatalf("Weekday: %v", err)
+			}
+			if err := d.SetProjectTime(qbistest.DevelopmentActivityID, 120); err != nil {
+				t.Fatalf("SetProjectTime: %v", err)
+			}
+			if test.workingHours {
+				setWorkingHours(t, w, time.Wednesday, 8*60, 17*60)
+			}
+			if err := store.UpdateTimesheet(monday, test.edit); err != nil {
+				t.Fatalf("UpdateTimesheet: %v", err)
+			}
+
+			if _, err := w.Save(); err != nil {
+				t.Fatalf("Save: %v", err)
+			}
+			sheet, err := store.Timesheet("1234", monday, monday.AddDate(0, 0, 6))
+			if err != nil {
+				t.Fatalf("Timesheet: %v", err)
+			}
+			if len(sheet.ListOfProjectTime) != 1 || sheet.ListOfProjectTime[0].Days[2].DayMinutes != 120 {
+				t.Errorf("project time was not saved: %+v", sheet.ListOfProjectTime)
+			}
+		})
+	}
+}
+
+func TestSaveWithForce(t *testing.T) {
+	store := qbistest.NewStore()
+	w := newWeek(t, store)
+	setWorkingHours(t, w, time.Wednesday, 8*60, 17*60)
+	editInQbis(t, store, 2, 9*60, 16*60)
+
+	if _, err := w.Save(qbis.WithForce()); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if arrival, departure := storedWorkingHours(t, store, 2); arrival != 8*60 || departure != 17*60 {
+		t.Errorf("qbis has %d-%d, want the forced 480-1020", arrival, departure)
+	}
+}
+
+func TestMergeThenSave(t *testing.T) {
+	store := qbistest.NewStore()
+	w := newWeek(t, store)
+	setWorkingHours(t, w, time.Wednesday, 8*60, 17*60)
+	editInQbis(t, store, 0, 9*60, 16*60)
+
+	// saving the working time would overwrite monday with the values it had when the week was loaded
+	_, err := w.Save()
+	var conflict *qbis.ConflictError
+	if !errors.As(err, &conflict) {
+		t.Fatalf("expected a *qbis.ConflictError, got %v", err)
+	}
+	if len(conflict.Conflicts) != 2 || conflict.Conflicts[0].Cell.Date != "2024-03-04" {
+		t.Errorf("expected monday to conflict, got %v", conflict.Conflicts)
+	}
+
+	if err := w.Merge(); err != nil {
+		t.Fatalf("Merge: %v", err)
+	}
+	if _, err := w.Save(); err != nil {
+		t.Fatalf("Save after Merge: %v", err)
+	}
+	if arrival, departure := storedWorkingHours(t, store, 0); arrival != 9*60 || departure != 16*60 {
+		t.Errorf("monday is %d-%d, want the change made in qbis 540-960", arrival, departure)
+	}
+	if arrival, departure := storedWorkingHours(t, store, 2); arrival != 8*60 || departure != 17*60 {
+		t.Errorf("wednesday is %d-%d, want the change made here 480-1020", arrival, departure)
+	}
+}
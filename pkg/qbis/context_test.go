@@ -0,0 +1,86 @@
+package qbis_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/flipb/qbis-time/pkg/qbis"
+	"github.com/flipb/qbis-time/pkg/qbis/api"
+	"github.com/flipb/qbis-time/pkg/qbis/qbistest"
+)
+
+type contextKey struct{}
+
+//contextAPI records the value of contextKey in the contexts of the calls that fetch activities
+type contextAPI struct {
+	*qbistest.MemoryAPI
+	values []interface{}
+}
+
+func (c *contextAPI) GetProjectActivityListContext(ctx context.Context, employee string, projectID int, from time.Time, to time.Time) ([]api.ProjectActivityListItem, error) {
+	c.values = append(c.values, ctx.Value(contextKey{}))
+	return c.MemoryAPI.GetProjectActivityListContext(ctx, employee, projectID, from, to)
+}
+
+func (c *contextAPI) GetSalaryActivityContext(ctx context.Context, employee string, activityID int, from time.Time, to time.Time) (*api.SalaryTime, error) {
+	c.values = append(c.values, ctx.Value(contextKey{}))
+	return c.MemoryAPI.GetSalaryActivityContext(ctx, employee, activityID, from, to)
+}
+
+func (c *contextAPI) GetProjectActivityContext(ctx context.Context, employee string, activityID int, from time.Time, to time.Time) (*api.ProjectTime, error) {
+	c.values = append(c.values, ctx.Value(contextKey{}))
+	return c.MemoryAPI.GetProjectActivityContext(ctx, employee, activityID, from, to)
+}
+
+func TestContextReachesActivityFetches(t *testing.T) {
+	backend := &contextAPI{MemoryAPI: qbistest.NewMemoryAPI(qbistest.NewStore())}
+	c, err := qbis.NewClientFromAPIClient(backend)
+	if err != nil {
+		t.Fatalf("NewClientFromAPIClient: %v", err)
+	}
+	w, err := c.Week(time.Date(2024, time.March, 6, 12, 0, 0, 0, time.Local))
+	if err != nil {
+		t.Fatalf("Week: %v", err)
+	}
+	d, err := w.Weekday(time.Wednesday)
+	if err != nil {
+		t.Fatalf("Weekday: %v", err)
+	}
+	ctx := context.WithValue(context.Background(), contextKey{}, "the caller's")
+
+	list, err := w.ProjectTimeActivitiesContext(ctx)
+	if err != nil {
+		t.Fatalf("ProjectTimeActivitiesContext: %v", err)
+	}
+	_, err = list.Activities()
+	if err != nil {
+		t.Fatalf("Activities: %v", err)
+	}
+	err = d.SetSalaryTimeContext(ctx, qbistest.SickLeaveActivityID, -60)
+	if err != nil {
+		t.Fatalf("SetSalaryTimeContext: %v", err)
+	}
+	err = d.SetProjectTimeContext(ctx, qbistest.DevelopmentActivityID, 60)
+	if err != nil {
+		t.Fatalf("SetProjectTimeContext: %v", err)
+	}
+	err = d.SetProjectTimeInternalNoteContext(ctx, qbistest.MeetingsActivityID, "note")
+	if err != nil {
+		t.Fatalf("SetProjectTimeInternalNoteContext: %v", err)
+	}
+	err = d.SetProjectTimeExternalNoteContext(ctx, qbistest.AdministrationActivityID, "note")
+	if err != nil {
+		t.Fatalf("SetProjectTimeExternalNoteContext: %v", err)
+	}
+
+	// two projects, a salary activity and three project activities that were not in the week
+	if len(backend.values) != 6 {
+		t.Fatalf("got %d activity fetches, want 6", len(backend.values))
+	}
+	for i, v := range backend.values {
+		if v != "the caller's" {
+			t.Errorf("fetch %d did not get the context of the caller", i)
+		}
+	}
+}
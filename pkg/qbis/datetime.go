@@ -1,40 +1,79 @@
 package qbis
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/flipb/qbis-time/pkg/qbis/api"
 )
 
 //getWeekSpan returns the first and last datetime of the week containing the given date
-// the datetimes returned are so called "qbis dates", datetimes with timestamp 00:00:00 and local timezone
-func getWeekSpan(date time.Time) (start, end time.Time, err error) {
-	start = date
-	for start.Weekday() != time.Monday {
-		start = start.AddDate(0, 0, -1)
-	}
-
-	start, err = api.GetDateForDateTime(start)
+// the datetimes returned are so called "qbis dates", datetimes with timestamp 00:00:00 in the time zone loc
+func getWeekSpan(date time.Time, loc *time.Location) (start, end time.Time, err error) {
+	day, err := api.GetDateForDateTimeIn(date, loc)
 	if err != nil {
-		return start, date, err
+		return date, date, err
 	}
-	/*
-		start = start.Add(-1 * time.Duration(start.Hour()) * time.Hour)
-		start = start.Add(-1 * time.Duration(start.Minute()) * time.Minute)
-		start = start.Add(-1 * time.Duration(start.Second()) * time.Second)
-	*/
-	end = date
-	for end.Weekday() != time.Sunday {
-		end = end.AddDate(0, 0, 1)
+
+	// count days from monday, time.Weekday starts the week on sunday
+	sinceMonday := (int(day.Weekday()) + 6) % 7
+
+	// use time.Date rather than adding durations so that days around DST changes are not 23 or 25 hours
+	start = time.Date(day.Year(), day.Month(), day.Day()-sinceMonday, 0, 0, 0, 0, loc)
+	end = time.Date(day.Year(), day.Month(), day.Day()-sinceMonday+6, 0, 0, 0, 0, loc)
+	return start, end, nil
+}
+
+//sameDate returns true if a and b are on the same calendar day in the time zone loc
+func sameDate(a time.Time, b time.Time, loc *time.Location) bool {
+	ay, am, ad := a.In(loc).Date()
+	by, bm, bd := b.In(loc).Date()
+	return ay == by && am == bm && ad == bd
+}
+
+//ClockTime is a time of day as Qbis stores it, in minutes since midnight. 24:00 is allowed for a departure at midnight.
+type ClockTime int
+
+//NewClockTime returns the clock time hour:minute
+func NewClockTime(hour int, minute int) (ClockTime, error) {
+	c := ClockTime(hour*60 + minute)
+	if hour < 0 || minute < 0 || minute > 59 || !c.Valid() {
+		return 0, fmt.Errorf("invalid clock time %02d:%02d", hour, minute)
 	}
-	end, err = api.GetDateForDateTime(end)
-	if err != nil {
-		return start, end, err
+	return c, nil
+}
+
+//ParseClockTime parses a clock time formatted as 15:04, eg. "8:30" or "17:00"
+func ParseClockTime(s string) (ClockTime, error) {
+	var hour, minute int
+	var rest string
+	n, _ := fmt.Sscanf(s, "%d:%d%s", &hour, &minute, &rest)
+	if n != 2 {
+		return 0, fmt.Errorf("invalid clock time %q, expected hh:mm", s)
 	}
-	/*
-		end = end.Add(time.Duration(23-end.Hour()) * time.Hour)
-		end = end.Add(time.Duration(59-end.Minute()) * time.Minute)
-		end = end.Add(time.Duration(59-end.Second()) * time.Second)
-	*/
-	return start, end, nil
+	return NewClockTime(hour, minute)
+}
+
+//clockTimeOf returns the time of day of t, in the time zone of t
+func clockTimeOf(t time.Time) ClockTime {
+	return ClockTime(t.Hour()*60 + t.Minute())
+}
+
+//Hour returns the hour of the clock time
+func (c ClockTime) Hour() int {
+	return int(c) / 60
+}
+
+//Minute returns the minute of the clock time
+func (c ClockTime) Minute() int {
+	return int(c) % 60
+}
+
+//Valid returns true if the clock time is between 00:00 and 24:00
+func (c ClockTime) Valid() bool {
+	return c >= 0 && c <= minutesPerDay
+}
+
+func (c ClockTime) String() string {
+	return fmt.Sprintf("%02d:%02d", c.Hour(), c.Minute())
 }
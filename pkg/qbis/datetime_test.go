@@ -0,0 +1,80 @@
+package qbis
+
+import (
+	"testing"
+	"time"
+
+	"github.com/flipb/qbis-time/pkg/qbis/api"
+)
+
+func TestWeekAcrossDST(t *testing.T) {
+	stockholm, err := time.LoadLocation("Europe/Stockholm")
+	if err != nil {
+		t.Skipf("no time zone data: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		monday time.Time
+		// hours is the length of every day of the week, the sunday is the last sunday of the month
+		hours [7]int
+	}{
+		{"summer time starts", time.Date(2024, time.March, 25, 0, 0, 0, 0, stockholm), [7]int{24, 24, 24, 24, 24, 24, 23}},
+		{"summer time ends", time.Date(2024, time.October, 21, 0, 0, 0, 0, stockholm), [7]int{24, 24, 24, 24, 24, 24, 25}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			next := test.monday.AddDate(0, 0, 7)
+			want := 0
+			for _, h := range test.hours {
+				want += h
+			}
+			if got := next.Sub(test.monday); got != time.Duration(want)*time.Hour {
+				t.Fatalf("the week is %v long, want %d hours", got, want)
+			}
+
+			// qbis sends the days as midnight in the time zone of the user, in utc
+			w := &Week{client: Client{location: stockholm}, sheet: &api.TimesheetData{}}
+			for i := 0; i < 7; i++ {
+				day := api.NewDate(test.monday.AddDate(0, 0, i).UTC())
+				w.sheet.DaySettings = append(w.sheet.DaySettings, api.DaySetting{DayDate: day})
+			}
+
+			// every hour of the week, in utc, is in the week and on the right day
+			hours := [7]int{}
+			for at := test.monday.UTC(); at.Before(next); at = at.Add(time.Hour) {
+				start, end, err := getWeekSpan(at, stockholm)
+				if err != nil {
+					t.Fatalf("getWeekSpan(%v): %v", at, err)
+				}
+				if !start.Equal(test.monday) || !end.Equal(test.monday.AddDate(0, 0, 6)) {
+					t.Fatalf("getWeekSpan(%v) = %v - %v, want %v - %v", at, start, end, test.monday, test.monday.AddDate(0, 0, 6))
+				}
+				if start.Hour() != 0 || end.Hour() != 0 || end.Weekday() != time.Sunday {
+					t.Fatalf("getWeekSpan(%v) = %v - %v, want monday to sunday at midnight", at, start, end)
+				}
+				if days := end.AddDate(0, 0, 1).Sub(start); days.Round(24*time.Hour) != 7*24*time.Hour {
+					t.Fatalf("getWeekSpan(%v) spans %v, want 7 days", at, days)
+				}
+
+				d, err := w.Day(at)
+				if err != nil {
+					t.Fatalf("Day(%v): %v", at, err)
+				}
+				midnight := test.monday.AddDate(0, 0, d.indexInWeek)
+				if !sameDate(at, midnight, stockholm) || !d.Date.Equal(midnight) {
+					t.Fatalf("Day(%v) is day %d of the week, starting %v", at, d.indexInWeek, d.Date)
+				}
+				hours[d.indexInWeek]++
+			}
+			if hours != test.hours {
+				t.Errorf("hours per day = %v, want %v", hours, test.hours)
+			}
+
+			_, err := w.Day(next)
+			if err == nil {
+				t.Errorf("Day(%v) found a day in the week before", next)
+			}
+		})
+	}
+}
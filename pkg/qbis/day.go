@@ -1,7 +1,9 @@
 package qbis
 
 import (
+	"context"
 	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/flipb/qbis-time/pkg/qbis/api"
@@ -30,43 +32,92 @@ func (d *Day) daySetting() *api.DaySetting {
 	return &d.week.sheet.DaySettings[d.indexInWeek]
 }
 
+//containsTime returns true if the time is on the date of the day, in the time zone of the client
 func (d *Day) containsTime(t time.Time) bool {
-	dy, dm, dd := d.Date.UTC().Date()
-	ty, tm, td := t.UTC().Date()
-	if ty == dy && tm == dm && td == dd {
-		return true
-	}
-	return false
+	return sameDate(d.Date, t, d.week.client.location)
 }
 
-//SetArrival sets time of arrival for the employee
+//SetArrival sets time of arrival for the employee. The time has to be on the date of the day in the time zone
+//of the client, and before the departure if that is set. See SetArrivalClock for arrivals at midnight.
 func (d *Day) SetArrival(time time.Time) error {
+	clock, err := d.clockTimeOf(time)
+	if err != nil {
+		return err
+	}
+	return d.SetArrivalClock(clock)
+}
+
+//SetDeparture sets time of departure for the employee. The time has to be on the date of the day in the time zone
+//of the client, and after the arrival if that is set. Use SetDepartureClock(24*60) to leave at midnight at the end of the day.
+func (d *Day) SetDeparture(time time.Time) error {
+	clock, err := d.clockTimeOf(time)
+	if err != nil {
+		return err
+	}
+	return d.SetDepartureClock(clock)
+}
+
+//clockTimeOf returns the time of day of the time in the time zone of the client, if it is on the date of the day
+func (d *Day) clockTimeOf(time time.Time) (ClockTime, error) {
 	if !d.containsTime(time) {
-		fmt.Errorf("date of day does not match date of supplied time: got %v, expected %v", time, d.Date)
+		return 0, fmt.Errorf("date of day does not match date of supplied time: got %v, expected %v", time, d.Date)
 	}
-	d.workingTime().Arrive = time.Hour()*60 + time.Minute()
+	return clockTimeOf(time.In(d.week.client.location)), nil
+}
+
+//SetArrivalClock sets time of arrival for the employee, from 00:00 to 23:59. It has to be before the departure if that is set.
+//Qbis stores a time that is not set as 0, so an arrival at 00:00 can not be told apart from no arrival:
+//it reads back as unset, and the departure is then not checked against it.
+func (d *Day) SetArrivalClock(arrival ClockTime) error {
+	if !arrival.Valid() || arrival >= minutesPerDay {
+		return fmt.Errorf("arrival is not a time of day: %d minutes", arrival)
+	}
+	if leave := ClockTime(d.workingTime().Leave); leave != 0 && arrival >= leave {
+		return fmt.Errorf("arrival %v has to be before departure %v", arrival, leave)
+	}
+	d.workingTime().Arrive = api.Minutes(arrival)
 	d.workingTime().IsModified = true
-	d.week.changed = true
+	d.week.markChanged(SectionWorkingTime)
+	return nil
+}
 
+//SetDepartureClock sets time of departure for the employee, where 24:00 is midnight at the end of the day.
+//It has to be after the arrival if that is set, see SetArrivalClock for why an arrival at 00:00 counts as not set.
+func (d *Day) SetDepartureClock(departure ClockTime) error {
+	if !departure.Valid() {
+		return fmt.Errorf("departure is not a time of day: %d minutes", departure)
+	}
+	if arrive := ClockTime(d.workingTime().Arrive); arrive != 0 && departure <= arrive {
+		return fmt.Errorf("departure %v has to be after arrival %v", departure, arrive)
+	}
+	d.workingTime().Leave = api.Minutes(departure)
+	d.workingTime().IsModified = true
+	d.week.markChanged(SectionWorkingTime)
 	return nil
 }
 
-//SetDeparture sets time of departure for the employee
-func (d *Day) SetDeparture(time time.Time) error {
-	if !d.containsTime(time) {
-		fmt.Errorf("date of day does not match date of supplied time: got %v, expected %v", time, d.Date)
+//SetWorkingHours sets both the arrival and the departure, eg. to move a day that is already set to later hours
+//where setting one at a time would put the arrival after the old departure. The arrival has to be before the departure,
+//which may be 24:00.
+func (d *Day) SetWorkingHours(arrival ClockTime, departure ClockTime) error {
+	if !arrival.Valid() || !departure.Valid() {
+		return fmt.Errorf("working hours are not times of day: %d and %d minutes", arrival, departure)
+	}
+	if arrival >= departure {
+		return fmt.Errorf("arrival %v has to be before departure %v", arrival, departure)
 	}
-	d.workingTime().Leave = time.Hour()*60 + time.Minute()
+	d.workingTime().Arrive = api.Minutes(arrival)
+	d.workingTime().Leave = api.Minutes(departure)
 	d.workingTime().IsModified = true
-	d.week.changed = true
+	d.week.markChanged(SectionWorkingTime)
 	return nil
 }
 
 //SetBreakMinutes sets the number of minutes the employee has been on lunch break
 func (d *Day) SetBreakMinutes(minutes uint) {
-	d.workingTime().Lunch = int(minutes)
+	d.workingTime().Lunch = api.Minutes(minutes)
 	d.workingTime().IsModified = true
-	d.week.changed = true
+	d.week.markChanged(SectionWorkingTime)
 }
 
 //ScheduledMinutes returns the number of minutes the employer thinks the employee is supposed to work
@@ -77,21 +128,38 @@ func (d *Day) ScheduledMinutes() uint {
 	return uint(d.daySetting().MySchedule.TotalMinutes)
 }
 
-//LoggedMinutes represents the number of minutes that the employee says s/he has worked
-func (d *Day) LoggedMinutes() uint {
-	if !d.workingTime().IsModified {
-		return uint(d.workingTime().Total)
-	}
+//LoggedMinutesError is returned by Day.LoggedMinutes when the working time of the day adds up to less than 0 minutes
+type LoggedMinutesError struct {
+	Date    time.Time
+	Arrive  api.Minutes
+	Leave   api.Minutes
+	Lunch   api.Minutes
+	Minutes int // what the working time adds up to
+}
 
-	// week has been modified, Total is not guaranteed to be up to date
-	// we have to calculate it
+func (e *LoggedMinutesError) Error() string {
+	return fmt.Sprintf("logged minutes of %s are negative: %d (arrive %s, leave %s, lunch %d minutes)",
+		e.Date.Format("2006-01-02"), e.Minutes,
+		formatValue("Arrive", strconv.Itoa(int(e.Arrive))), formatValue("Leave", strconv.Itoa(int(e.Leave))), e.Lunch)
+}
 
-	// check if it makes sense.
-	if (d.workingTime().Leave - d.workingTime().Arrive - d.workingTime().Lunch) < 0 {
-		panic(fmt.Errorf("Logged minutes calculation less than 0"))
+//LoggedMinutes represents the number of minutes that the employee says s/he has worked.
+//If they add up to less than 0, eg. because the departure is set but not the arrival, a *LoggedMinutesError is returned.
+func (d *Day) LoggedMinutes() (uint, error) {
+	wt := d.workingTime()
+	logged := int(wt.Total)
+
+	if wt.IsModified {
+		// week has been modified, Total is not guaranteed to be up to date
+		// we have to calculate it
+		logged = int(wt.Leave - wt.Arrive - wt.Lunch)
 	}
 
-	return uint(d.workingTime().Leave - d.workingTime().Arrive - d.workingTime().Lunch)
+	// check if it makes sense.
+	if logged < 0 {
+		return 0, &LoggedMinutesError{Date: d.Date, Arrive: wt.Arrive, Leave: wt.Leave, Lunch: wt.Lunch, Minutes: logged}
+	}
+	return uint(logged), nil
 }
 
 //Holiday returns true if the day is a holiday
@@ -119,16 +187,21 @@ func (d *Day) LoggedSalaryTimeActivities() []SalaryActivity {
 
 //SalaryTimeMinutes returns the number of minutes registered on the given salary activity that day
 func (d *Day) SalaryTimeMinutes(activityID int) int {
-	salaryTime, err := d.week.salaryTime(activityID)
+	salaryTime, err := d.week.salaryTime(context.Background(), activityID)
 	if err != nil {
 		return 0
 	}
-	return salaryTime.Days[d.indexInWeek].DayMinutes
+	return int(salaryTime.Days[d.indexInWeek].DayMinutes)
 }
 
 //SetSalaryTime sets the number of minutes spent on the activity that day
 func (d *Day) SetSalaryTime(activityID int, minutes int) error {
-	salaryTime, err := d.week.salaryTime(activityID)
+	return d.SetSalaryTimeContext(context.Background(), activityID, minutes)
+}
+
+//SetSalaryTimeContext is like SetSalaryTime but with a context, used to fetch the activity if it is not in the week yet
+func (d *Day) SetSalaryTimeContext(ctx context.Context, activityID int, minutes int) error {
+	salaryTime, err := d.week.salaryTime(ctx, activityID)
 	if err != nil {
 		return fmt.Errorf("error getting activity with id %d : %v", activityID, err)
 	}
@@ -141,8 +214,8 @@ func (d *Day) SetSalaryTime(activityID int, minutes int) error {
 		return fmt.Errorf("salaryTime activity %s (%d) does not allow positive minutes: %d", salaryTime.ActivityName, activityID, minutes)
 	}
 
-	salaryTime.Days[d.indexInWeek].DayMinutes = minutes
-	d.week.changed = true
+	salaryTime.Days[d.indexInWeek].DayMinutes = api.Minutes(minutes)
+	d.week.markChanged(SectionSalaryTime)
 	return nil
 }
 
@@ -165,16 +238,16 @@ func (d *Day) LoggedProjectTimeActivities() []ProjectActivity {
 
 //ProjectTimeMinutes returns the number of minutes registered on the given project activity that day
 func (d *Day) ProjectTimeMinutes(activityID int) int {
-	projectTime, err := d.week.projectTime(activityID)
+	projectTime, err := d.week.projectTime(context.Background(), activityID)
 	if err != nil {
 		return 0
 	}
-	return projectTime.Days[d.indexInWeek].DayMinutes
+	return int(projectTime.Days[d.indexInWeek].DayMinutes)
 }
 
 //ProjectTimeInternalNotes returns internal notes on the project activity the given day
 func (d *Day) ProjectTimeInternalNotes(activityID int) string {
-	projectTime, err := d.week.projectTime(activityID)
+	projectTime, err := d.week.projectTime(context.Background(), activityID)
 	if err != nil {
 		return ""
 	}
@@ -183,7 +256,7 @@ func (d *Day) ProjectTimeInternalNotes(activityID int) string {
 
 //ProjectTimeExternalNotes returns external notes on the project activity the given day
 func (d *Day) ProjectTimeExternalNotes(activityID int) string {
-	projectTime, err := d.week.projectTime(activityID)
+	projectTime, err := d.week.projectTime(context.Background(), activityID)
 	if err != nil {
 		return ""
 	}
@@ -192,36 +265,51 @@ func (d *Day) ProjectTimeExternalNotes(activityID int) string {
 
 //SetProjectTime sets the number of minutes spent on the activity that day
 func (d *Day) SetProjectTime(activityID int, minutes int) error {
-	projectTime, err := d.week.projectTime(activityID)
+	return d.SetProjectTimeContext(context.Background(), activityID, minutes)
+}
+
+//SetProjectTimeContext is like SetProjectTime but with a context, used to fetch the activity if it is not in the week yet
+func (d *Day) SetProjectTimeContext(ctx context.Context, activityID int, minutes int) error {
+	projectTime, err := d.week.projectTime(ctx, activityID)
 	if err != nil {
 		return fmt.Errorf("error getting activity with id %d : %v", activityID, err)
 	}
 
-	projectTime.Days[d.indexInWeek].DayMinutes = minutes
-	d.week.changed = true
+	projectTime.Days[d.indexInWeek].DayMinutes = api.Minutes(minutes)
+	d.week.markChanged(SectionProjectTime)
 	return nil
 }
 
 //SetProjectTimeInternalNote sets the internal note on the activity that day
 func (d *Day) SetProjectTimeInternalNote(activityID int, note string) error {
-	projectTime, err := d.week.projectTime(activityID)
+	return d.SetProjectTimeInternalNoteContext(context.Background(), activityID, note)
+}
+
+//SetProjectTimeInternalNoteContext is like SetProjectTimeInternalNote but with a context, used to fetch the activity if it is not in the week yet
+func (d *Day) SetProjectTimeInternalNoteContext(ctx context.Context, activityID int, note string) error {
+	projectTime, err := d.week.projectTime(ctx, activityID)
 	if err != nil {
 		return fmt.Errorf("error getting activity with id %d : %v", activityID, err)
 	}
 
 	projectTime.Days[d.indexInWeek].InternalNotes = note
-	d.week.changed = true
+	d.week.markChanged(SectionProjectTime)
 	return nil
 }
 
 //SetProjectTimeExternalNote sets the external note on the activity that day
 func (d *Day) SetProjectTimeExternalNote(activityID int, note string) error {
-	projectTime, err := d.week.projectTime(activityID)
+	return d.SetProjectTimeExternalNoteContext(context.Background(), activityID, note)
+}
+
+//SetProjectTimeExternalNoteContext is like SetProjectTimeExternalNote but with a context, used to fetch the activity if it is not in the week yet
+func (d *Day) SetProjectTimeExternalNoteContext(ctx context.Context, activityID int, note string) error {
+	projectTime, err := d.week.projectTime(ctx, activityID)
 	if err != nil {
 		return fmt.Errorf("error getting activity with id %d : %v", activityID, err)
 	}
 
 	projectTime.Days[d.indexInWeek].ExternalNotes = note
-	d.week.changed = true
+	d.week.markChanged(SectionProjectTime)
 	return nil
 }
@@ -0,0 +1,153 @@
+package qbis_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/flipb/qbis-time/pkg/qbis"
+	"github.com/flipb/qbis-time/pkg/qbis/qbistest"
+)
+
+func TestSetClockTimes(t *testing.T) {
+	tests := []struct {
+		name      string
+		arrival   qbis.ClockTime // set first, unless 0
+		departure qbis.ClockTime // set second, unless 0
+		ok        bool
+	}{
+		{"day", 8 * 60, 17 * 60, true},
+		{"departure at midnight", 8 * 60, 24 * 60, true},
+		{"arrival at midnight", 24 * 60, 0, false},
+		{"arrival after the end of the day", 25 * 60, 0, false},
+		{"departure after the end of the day", 0, 24*60 + 1, false},
+		{"departure before arrival", 17 * 60, 8 * 60, false},
+		{"departure at arrival", 8 * 60, 8 * 60, false},
+		{"arrival at 00:00 is not set", 0, 1, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			d := newDay(t)
+			var err error
+			if test.arrival != 0 {
+				err = d.SetArrivalClock(test.arrival)
+			}
+			if err == nil && test.departure != 0 {
+				err = d.SetDepartureClock(test.departure)
+			}
+			if test.ok && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if !test.ok && err == nil {
+				t.Error("expected an error")
+			}
+
+			if test.arrival != 0 && test.departure != 0 {
+				err = newDay(t).SetWorkingHours(test.arrival, test.departure)
+				if test.ok != (err == nil) {
+					t.Errorf("SetWorkingHours: got %v, want ok %v", err, test.ok)
+				}
+			}
+		})
+	}
+}
+
+func TestSetArrivalAndDeparture(t *testing.T) {
+	stockholm, err := time.LoadLocation("Europe/Stockholm")
+	if err != nil {
+		t.Skipf("no time zone data: %v", err)
+	}
+	at := func(day int, hour int, minute int, loc *time.Location) time.Time {
+		return time.Date(2024, time.March, day, hour, minute, 0, 0, loc)
+	}
+	newYork := time.FixedZone("EST", -5*60*60)
+
+	tests := []struct {
+		name           string
+		arrival        time.Time // set first, unless zero
+		departure      time.Time // set second, unless zero
+		departureFirst bool
+		arrive         string // the arrival in minutes after the changes, empty if not set
+		leave          string
+		ok             bool
+	}{
+		{"in the time zone of the client", at(6, 8, 0, stockholm), at(6, 17, 0, stockholm), false, "480", "1020", true},
+		{"utc the day before", at(5, 23, 30, time.UTC), at(6, 16, 0, time.UTC), false, "30", "1020", true},
+		{"another time zone", at(6, 2, 0, newYork), at(6, 12, 0, newYork), false, "480", "1080", true},
+		{"arrival on the day before", at(5, 8, 0, stockholm), time.Time{}, false, "", "", false},
+		{"arrival on the day after in utc", at(6, 23, 30, time.UTC), time.Time{}, false, "", "", false},
+		{"departure on the day after", time.Time{}, at(7, 8, 0, stockholm), false, "", "", false},
+		{"departure before arrival", at(6, 17, 0, stockholm), at(6, 8, 0, stockholm), false, "1020", "", false},
+		{"arrival after departure", at(6, 17, 0, stockholm), at(6, 8, 0, stockholm), true, "", "480", false},
+		{"departure at arrival in another time zone", at(6, 8, 0, stockholm), at(6, 7, 0, time.UTC), false, "480", "", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store := qbistest.NewStore()
+			store.Location = stockholm
+			c, err := qbis.NewClientFromAPIClient(qbistest.NewMemoryAPI(store), qbis.WithLocation(stockholm))
+			if err != nil {
+				t.Fatalf("NewClientFromAPIClient: %v", err)
+			}
+			w, err := c.Week(at(6, 12, 0, stockholm))
+			if err != nil {
+				t.Fatalf("Week: %v", err)
+			}
+			d, err := w.Weekday(time.Wednesday)
+			if err != nil {
+				t.Fatalf("Weekday: %v", err)
+			}
+
+			setters := []func() error{
+				func() error {
+					if test.arrival.IsZero() {
+						return nil
+					}
+					return d.SetArrival(test.arrival)
+				},
+				func() error {
+					if test.departure.IsZero() {
+						return nil
+					}
+					return d.SetDeparture(test.departure)
+				},
+			}
+			if test.departureFirst {
+				setters[0], setters[1] = setters[1], setters[0]
+			}
+			for _, set := range setters {
+				if err = set(); err != nil {
+					break
+				}
+			}
+			if test.ok != (err == nil) {
+				t.Errorf("got %v, want ok %v", err, test.ok)
+			}
+
+			got := map[string]string{"Arrive": "", "Leave": ""}
+			for _, change := range w.Diff() {
+				got[change.Field] = change.New
+			}
+			if got["Arrive"] != test.arrive || got["Leave"] != test.leave {
+				t.Errorf("working hours are %q-%q, want %q-%q", got["Arrive"], got["Leave"], test.arrive, test.leave)
+			}
+		})
+	}
+}
+
+//newDay returns the wednesday of an empty week
+func newDay(t *testing.T) *qbis.Day {
+	t.Helper()
+	c, err := qbis.NewClientFromAPIClient(qbistest.NewMemoryAPI(qbistest.NewStore()))
+	if err != nil {
+		t.Fatalf("NewClientFromAPIClient: %v", err)
+	}
+	w, err := c.Week(time.Date(2024, time.March, 6, 12, 0, 0, 0, time.Local))
+	if err != nil {
+		t.Fatalf("Week: %v", err)
+	}
+	d, err := w.Weekday(time.Wednesday)
+	if err != nil {
+		t.Fatalf("Weekday: %v", err)
+	}
+	return d
+}
@@ -0,0 +1,130 @@
+package qbis
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//fieldNames are the human readable names of the cell fields
+var fieldNames = map[string]string{
+	"Arrive":        "arrive",
+	"Leave":         "leave",
+	"Lunch":         "lunch",
+	"DayMinutes":    "minutes",
+	"DayDays":       "days",
+	"Notes":         "notes",
+	"InternalNotes": "internal notes",
+	"ExternalNotes": "external notes",
+}
+
+//Change is a value in the week that has been changed but not saved
+type Change struct {
+	Cell
+	Day          time.Time // midnight of the day, in the time zone of the client
+	ActivityName string    // empty for working time
+	Old          string
+	New          string
+}
+
+//FieldName returns the human readable name of the field, eg. "internal notes"
+func (c Change) FieldName() string {
+	if name, ok := fieldNames[c.Field]; ok {
+		return name
+	}
+	return c.Field
+}
+
+//String renders the change on one line, eg. "Mon 2018-03-12 working time arrive: 08:00 -> 09:00"
+func (c Change) String() string {
+	where := c.Section.String()
+	if c.Section != SectionWorkingTime {
+		where = fmt.Sprintf("%s %s (%d)", where, c.ActivityName, c.Activity)
+	}
+	return fmt.Sprintf("%s %s %s %s: %s -> %s", c.Day.Format("Mon"), c.Date, where, c.FieldName(), formatValue(c.Field, c.Old), formatValue(c.Field, c.New))
+}
+
+//formatValue renders the value of a field for people: clock times as 08:00, durations as 1:30 like Qbis does and notes quoted
+func formatValue(field string, value string) string {
+	switch field {
+	case "Arrive", "Leave":
+		minutes, err := strconv.Atoi(value)
+		if value == "" || err != nil {
+			return "--:--"
+		}
+		return fmt.Sprintf("%02d:%02d", minutes/60, minutes%60)
+	case "Lunch", "DayMinutes":
+		minutes, err := strconv.Atoi(value)
+		if value == "" || err != nil {
+			return "0:00"
+		}
+		sign := ""
+		if minutes < 0 {
+			sign, minutes = "-", -minutes
+		}
+		return fmt.Sprintf("%s%d:%02d", sign, minutes/60, minutes%60)
+	case "DayDays":
+		if value == "" {
+			return "0"
+		}
+		return value
+	default:
+		return strconv.Quote(value)
+	}
+}
+
+//Diff returns the changes made to the week since it was loaded or last saved, in the order they would be saved
+func (w *Week) Diff() []Change {
+	changes := make([]Change, 0)
+	if w.original == nil || w.sheet == nil {
+		return changes
+	}
+
+	original := w.flatten(w.original)
+	edited := w.flatten(w.sheet)
+	changed := make(map[Cell]bool)
+	for _, set := range []cells{original, edited} {
+		for c := range set {
+			if original[c] != edited[c] {
+				changed[c] = true
+			}
+		}
+	}
+
+	// sections sort in the order they are saved
+	for _, c := range sortedCells(changed) {
+		changes = append(changes, Change{
+			Cell:         c,
+			Day:          w.start.AddDate(0, 0, w.dayIndex(c.Date)),
+			ActivityName: w.activityName(c),
+			Old:          original[c],
+			New:          edited[c],
+		})
+	}
+	return changes
+}
+
+//FormatDiff renders the changes one per line, eg. to ask for confirmation before saving
+func FormatDiff(changes []Change) string {
+	lines := make([]string, 0, len(changes))
+	for _, c := range changes {
+		lines = append(lines, c.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+//activityName returns the name of the activity of the cell
+func (w *Week) activityName(c Cell) string {
+	switch c.Section {
+	case SectionSalaryTime:
+		if row := salaryRow(w.sheet, c.Activity); row != nil {
+			return row.ActivityName
+		}
+	case SectionProjectTime:
+		if row := projectRow(w.sheet, c.Activity); row != nil {
+			return row.ActivityName
+		}
+	}
+	return ""
+}
@@ -0,0 +1,103 @@
+package qbis_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/flipb/qbis-time/pkg/qbis"
+	"github.com/flipb/qbis-time/pkg/qbis/qbistest"
+)
+
+func TestDiff(t *testing.T) {
+	w := newWeek(t, qbistest.NewStore())
+	if changes := w.Diff(); len(changes) != 0 {
+		t.Fatalf("loaded week has changes: %v", changes)
+	}
+
+	d, err := w.Weekday(time.Wednesday)
+	if err != nil {
+		t.Fatalf("Weekday: %v", err)
+	}
+	if err := d.SetWorkingHours(8*60, 17*60); err != nil {
+		t.Fatalf("SetWorkingHours: %v", err)
+	}
+	d.SetBreakMinutes(45)
+	if err := d.SetSalaryTime(qbistest.SickLeaveActivityID, -90); err != nil {
+		t.Fatalf("SetSalaryTime: %v", err)
+	}
+	if err := d.SetProjectTime(qbistest.DevelopmentActivityID, 90); err != nil {
+		t.Fatalf("SetProjectTime: %v", err)
+	}
+	if err := d.SetProjectTimeInternalNote(qbistest.DevelopmentActivityID, "standup"); err != nil {
+		t.Fatalf("SetProjectTimeInternalNote: %v", err)
+	}
+
+	wednesday := "2024-03-06"
+	want := []struct {
+		cell qbis.Cell
+		old  string
+		new  string
+		line string
+	}{
+		{
+			qbis.Cell{Section: qbis.SectionSalaryTime, Activity: qbistest.SickLeaveActivityID, Date: wednesday, Field: "DayMinutes"},
+			"", "-90",
+			"Wed 2024-03-06 salary time Sick leave (10) minutes: 0:00 -> -1:30",
+		},
+		{
+			qbis.Cell{Section: qbis.SectionWorkingTime, Date: wednesday, Field: "Arrive"},
+			"", "480",
+			"Wed 2024-03-06 working time arrive: --:-- -> 08:00",
+		},
+		{
+			qbis.Cell{Section: qbis.SectionWorkingTime, Date: wednesday, Field: "Leave"},
+			"", "1020",
+			"Wed 2024-03-06 working time leave: --:-- -> 17:00",
+		},
+		{
+			qbis.Cell{Section: qbis.SectionWorkingTime, Date: wednesday, Field: "Lunch"},
+			"", "45",
+			"Wed 2024-03-06 working time lunch: 0:00 -> 0:45",
+		},
+		{
+			qbis.Cell{Section: qbis.SectionProjectTime, Activity: qbistest.DevelopmentActivityID, Date: wednesday, Field: "DayMinutes"},
+			"", "90",
+			"Wed 2024-03-06 project time Development (1001) minutes: 0:00 -> 1:30",
+		},
+		{
+			qbis.Cell{Section: qbis.SectionProjectTime, Activity: qbistest.DevelopmentActivityID, Date: wednesday, Field: "InternalNotes"},
+			"", "standup",
+			`Wed 2024-03-06 project time Development (1001) internal notes: "" -> "standup"`,
+		},
+	}
+
+	changes := w.Diff()
+	if len(changes) != len(want) {
+		t.Fatalf("got %d changes, want %d:\n%s", len(changes), len(want), qbis.FormatDiff(changes))
+	}
+	lines := make([]string, 0, len(want))
+	for i, c := range changes {
+		if c.Cell != want[i].cell || c.Old != want[i].old || c.New != want[i].new {
+			t.Errorf("change %d is %v %q -> %q, want %v %q -> %q", i, c.Cell, c.Old, c.New, want[i].cell, want[i].old, want[i].new)
+		}
+		if !c.Day.Equal(monday.AddDate(0, 0, 2)) {
+			t.Errorf("change %d is on %v, want wednesday", i, c.Day)
+		}
+		if c.String() != want[i].line {
+			t.Errorf("change %d renders as %q, want %q", i, c.String(), want[i].line)
+		}
+		lines = append(lines, want[i].line)
+	}
+	if got := qbis.FormatDiff(changes); got != strings.Join(lines, "\n") {
+		t.Errorf("FormatDiff:\n%s\nwant:\n%s", got, strings.Join(lines, "\n"))
+	}
+
+	// changing a value back is not a change
+	if err := d.SetProjectTimeInternalNote(qbistest.DevelopmentActivityID, ""); err != nil {
+		t.Fatalf("SetProjectTimeInternalNote: %v", err)
+	}
+	if changes := w.Diff(); len(changes) != len(want)-1 {
+		t.Errorf("got %d changes after clearing the note, want %d", len(changes), len(want)-1)
+	}
+}
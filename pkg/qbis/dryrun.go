@@ -0,0 +1,76 @@
+package qbis
+
+import (
+	"fmt"
+
+	"github.com/flipb/qbis-time/pkg/qbis/api"
+)
+
+//DryRunResult is what Week.Save would send to Qbis. The payloads of the sections that would not be saved are nil.
+type DryRunResult struct {
+	Sections []Section // the sections that would be saved, in order
+
+	SalaryTime  *api.EmployeeSalaryTime
+	WorkingTime *api.EmployeeWorkingTime
+	ProjectTime *api.EmployeeProjectTime
+
+	Changes []Change
+	Errors  ValidationErrors
+}
+
+//Valid returns true if no validation errors were found
+func (r *DryRunResult) Valid() bool {
+	return len(r.Errors) == 0
+}
+
+//Err returns the validation errors, or nil if there are none
+func (r *DryRunResult) Err() error {
+	if r.Valid() {
+		return nil
+	}
+	return r.Errors
+}
+
+//JSON returns the body that would be posted to save the section, exactly as the api client encodes it
+func (r *DryRunResult) JSON(section Section) ([]byte, error) {
+	var payload interface{}
+	switch {
+	case section == SectionSalaryTime && r.SalaryTime != nil:
+		payload = r.SalaryTime
+	case section == SectionWorkingTime && r.WorkingTime != nil:
+		payload = r.WorkingTime
+	case section == SectionProjectTime && r.ProjectTime != nil:
+		payload = r.ProjectTime
+	default:
+		return nil, fmt.Errorf("%v would not be saved", section)
+	}
+	return api.EncodePayload(payload)
+}
+
+//DryRun builds the payloads Save would send for the changed sections and checks the changes with the rules of the client,
+//without sending anything. The payloads are built from a copy of the week, so changing them does not change the week.
+func (w *Week) DryRun() (*DryRunResult, error) {
+	sheet, err := cloneSheet(w.sheet)
+	if err != nil {
+		return nil, fmt.Errorf("error copying week: %w", err)
+	}
+	result := &DryRunResult{
+		Sections: w.ChangedSections(),
+		Changes:  w.Diff(),
+	}
+	for _, section := range result.Sections {
+		switch section {
+		case SectionSalaryTime:
+			payload := w.salaryTimePayload(sheet)
+			result.SalaryTime = &payload
+		case SectionWorkingTime:
+			payload := w.workingTimePayload(sheet)
+			result.WorkingTime = &payload
+		case SectionProjectTime:
+			payload := w.projectTimePayload(sheet)
+			result.ProjectTime = &payload
+		}
+	}
+	result.Errors = w.client.validator.Validate(w, result.Changes)
+	return result, nil
+}
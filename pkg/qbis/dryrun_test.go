@@ -0,0 +1,85 @@
+package qbis_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/flipb/qbis-time/pkg/qbis"
+	"github.com/flipb/qbis-time/pkg/qbis/qbistest"
+)
+
+//bodyRecorder keeps the bodies of the requests posted to paths ending with suffix
+type bodyRecorder struct {
+	suffix string
+	bodies [][]byte
+}
+
+func (r *bodyRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil && strings.HasSuffix(req.URL.Path, r.suffix) {
+		body, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		r.bodies = append(r.bodies, body)
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestDryRunSendsTheSameBytesAsSave(t *testing.T) {
+	srv := qbistest.NewServer()
+	defer srv.Close()
+	recorder := &bodyRecorder{suffix: "/SaveSalaryTime"}
+
+	c, err := qbis.NewClient(qbistest.DefaultCompany, qbistest.DefaultUser, qbistest.DefaultPassword,
+		qbis.WithBaseURL(srv.URL), qbis.WithHTTPClient(&http.Client{Transport: recorder}))
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	wednesday := time.Date(2024, time.March, 6, 12, 0, 0, 0, time.Local)
+	w, err := c.Week(wednesday)
+	if err != nil {
+		t.Fatalf("Week: %v", err)
+	}
+	d, err := w.Weekday(time.Wednesday)
+	if err != nil {
+		t.Fatalf("Weekday: %v", err)
+	}
+	if err := d.SetSalaryTime(qbistest.SickLeaveActivityID, -60); err != nil {
+		t.Fatalf("SetSalaryTime: %v", err)
+	}
+
+	preview, err := w.DryRun()
+	if err != nil {
+		t.Fatalf("DryRun: %v", err)
+	}
+	payload, err := preview.JSON(qbis.SectionSalaryTime)
+	if err != nil {
+		t.Fatalf("JSON: %v", err)
+	}
+
+	// changing the payload does not change the week
+	for _, row := range preview.SalaryTime.SalaryTime {
+		for i := range row.Days {
+			row.Days[i].DayMinutes = 999
+		}
+	}
+	if got := d.SalaryTimeMinutes(qbistest.SickLeaveActivityID); got != -60 {
+		t.Fatalf("salary time = %d after changing the dry run payload, want -60", got)
+	}
+
+	_, err = w.Save(qbis.WithoutRollback())
+	if err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if len(recorder.bodies) != 1 {
+		t.Fatalf("salary time was posted %d times, want once", len(recorder.bodies))
+	}
+	if !bytes.Equal(recorder.bodies[0], payload) {
+		t.Errorf("dry run payload differs from the saved one:\n%s\n%s", payload, recorder.bodies[0])
+	}
+}
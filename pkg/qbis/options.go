@@ -0,0 +1,137 @@
+package qbis
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/flipb/qbis-time/pkg/qbis/api"
+)
+
+//Option configures the Client created by NewClient
+type Option func(*options)
+
+type options struct {
+	url        string
+	httpClient *http.Client
+	language   string
+	location   *time.Location
+	logger     api.Logger
+	store      api.SessionStore
+	drift      api.SchemaDriftHandler
+
+	rules          []Rule
+	noDefaultRules bool
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{
+		url:      defaultURL,
+		language: api.DefaultLanguage,
+		location: time.Local,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+//apiClient creates a new api client for the options
+func (o *options) apiClient(url string) *api.Client {
+	client := api.New(url).WithLanguage(o.language)
+	if o.httpClient != nil {
+		client.WithHTTPClient(o.httpClient)
+	}
+	if o.logger != nil {
+		client.WithLogger(o.logger)
+	}
+	if o.drift != nil {
+		client.WithSchemaDriftHandler(o.drift)
+	}
+	if o.store != nil {
+		// keep the stored session alive across restarts
+		client.WithReloginHandler(func(c *api.Client) {
+			err := saveSession(c, o.store)
+			if err != nil && o.logger != nil {
+				o.logger.Printf("%v", err)
+			}
+		})
+	}
+	return client
+}
+
+//validator creates the validator with the rules of the options
+func (o *options) validator() *Validator {
+	rules := make([]Rule, 0)
+	if !o.noDefaultRules {
+		rules = append(rules, DefaultRules()...)
+	}
+	return NewValidator(append(rules, o.rules...)...)
+}
+
+//WithBaseURL sets the address of the Qbis service, eg. to use a staging tenant or a test server
+func WithBaseURL(url string) Option {
+	return func(o *options) {
+		o.url = url
+	}
+}
+
+//WithHTTPClient sets the http.Client used to talk to Qbis
+func WithHTTPClient(client *http.Client) Option {
+	return func(o *options) {
+		o.httpClient = client
+	}
+}
+
+//WithLanguage sets the language used when logging in (default "lang_english")
+func WithLanguage(language string) Option {
+	return func(o *options) {
+		o.language = language
+	}
+}
+
+//WithLocation sets the time zone of the Qbis user (default time.Local)
+func WithLocation(location *time.Location) Option {
+	return func(o *options) {
+		if location != nil {
+			o.location = location
+		}
+	}
+}
+
+//WithLogger sets a logger for debug output
+func WithLogger(logger api.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+//WithSessionStore makes the client reuse the session in the store if it is still valid and was made by the same user at the same url,
+//and save the session in the store when it has to log in
+func WithSessionStore(store api.SessionStore) Option {
+	return func(o *options) {
+		o.store = store
+	}
+}
+
+//WithSchemaDriftHandler makes the client compare every Qbis response to the types of the api package
+//and report the differences to the handler, see api.Client.WithSchemaDriftHandler
+func WithSchemaDriftHandler(handler api.SchemaDriftHandler) Option {
+	return func(o *options) {
+		o.drift = handler
+	}
+}
+
+//WithRules adds rules that the changes to a week have to follow before Week.Save sends them,
+//eg. to enforce the policies of your team
+func WithRules(rules ...Rule) Option {
+	return func(o *options) {
+		o.rules = append(o.rules, rules...)
+	}
+}
+
+//WithoutDefaultRules leaves out the rules returned by DefaultRules, only the rules added with WithRules are checked
+func WithoutDefaultRules() Option {
+	return func(o *options) {
+		o.noDefaultRules = true
+	}
+}
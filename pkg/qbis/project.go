@@ -1,23 +1,30 @@
 package qbis
 
-import "github.com/flipb/qbis-time/pkg/qbis/api"
+import (
+	"context"
+
+	"github.com/flipb/qbis-time/pkg/qbis/api"
+)
 
 //ProjectActivityList contains all companies, projects and activities available to the employee
 type ProjectActivityList struct {
 	week *Week
+	// ctx is the context the list was fetched with, also used to fetch the activities of the projects
+	ctx context.Context
 
 	companies []api.ProjectCompany
 }
 
 //newProjectActivityList initialized a new ProjectActivityList for the given week
-func newProjectActivityList(week *Week) (*ProjectActivityList, error) {
-	companies, err := week.client.apiClient.GetProjects(week.client.employeeID, week.start, week.end)
+func newProjectActivityList(ctx context.Context, week *Week) (*ProjectActivityList, error) {
+	companies, err := week.client.apiClient.GetProjectsContext(ctx, week.client.employeeID, week.start, week.end)
 	if err != nil {
 		return nil, err
 	}
 
 	return &ProjectActivityList{
 		week:      week,
+		ctx:       ctx,
 		companies: companies,
 	}, nil
 }
@@ -89,13 +96,13 @@ func (pc ProjectCompany) CompanyID() int {
 	return pc.company.CompanyID
 }
 
-//Projects gets a list of all projects for the company
+//Projects gets a list of all projects for the company, fetching their activities with the context of the list
 func (pc *ProjectCompany) Projects() ([]Project, error) {
 	var projects = make([]Project, 0)
 	for i, p := range pc.company.Projects {
 
 		// get activities
-		list, err := pc.list.week.client.apiClient.GetProjectActivityList(pc.list.week.client.employeeID, p.ID, pc.list.week.start, pc.list.week.end)
+		list, err := pc.list.week.client.apiClient.GetProjectActivityListContext(pc.list.ctx, pc.list.week.client.employeeID, p.ID, pc.list.week.start, pc.list.week.end)
 		if err != nil {
 			return nil, err
 		}
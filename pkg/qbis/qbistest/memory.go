@@ -0,0 +1,96 @@
+package qbistest
+
+import (
+	"context"
+	"time"
+
+	"github.com/flipb/qbis-time/pkg/qbis"
+	"github.com/flipb/qbis-time/pkg/qbis/api"
+)
+
+//MemoryAPI implements qbis.API on top of a Store, without any http.
+//Use it with qbis.NewClientFromAPIClient to unit test code built on qbis.Week:
+//
+//	client, err := qbis.NewClientFromAPIClient(qbistest.NewMemoryAPI(qbistest.NewStore()))
+type MemoryAPI struct {
+	Store *Store
+}
+
+var _ qbis.API = (*MemoryAPI)(nil)
+
+//NewMemoryAPI returns a MemoryAPI serving the store
+func NewMemoryAPI(store *Store) *MemoryAPI {
+	return &MemoryAPI{Store: store}
+}
+
+//GetLoginInfoContext returns the profile of the employee in the store
+func (m *MemoryAPI) GetLoginInfoContext(ctx context.Context) (*api.LoginInfo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return m.Store.LoginInfo(), nil
+}
+
+//GetTimesheetContext returns the timesheet of the week
+func (m *MemoryAPI) GetTimesheetContext(ctx context.Context, employee string, from time.Time, to time.Time) (*api.TimesheetData, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return m.Store.Timesheet(employee, from, to)
+}
+
+//GetProjectsContext returns the companies and projects of the employee
+func (m *MemoryAPI) GetProjectsContext(ctx context.Context, employee string, from time.Time, to time.Time) ([]api.ProjectCompany, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return m.Store.ProjectCompanies(employee)
+}
+
+//GetProjectActivityListContext returns the activities of the project
+func (m *MemoryAPI) GetProjectActivityListContext(ctx context.Context, employee string, projectID int, from time.Time, to time.Time) ([]api.ProjectActivityListItem, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return m.Store.ProjectActivityList(employee, projectID)
+}
+
+//GetProjectActivityContext returns an empty week row for the project activity
+func (m *MemoryAPI) GetProjectActivityContext(ctx context.Context, employee string, activityID int, from time.Time, to time.Time) (*api.ProjectTime, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return m.Store.ProjectActivity(employee, activityID, from)
+}
+
+//GetSalaryActivityContext returns an empty week row for the salary activity
+func (m *MemoryAPI) GetSalaryActivityContext(ctx context.Context, employee string, activityID int, from time.Time, to time.Time) (*api.SalaryTime, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return m.Store.SalaryActivity(employee, activityID, from)
+}
+
+//SaveSalaryTimeContext saves the salary time in the store
+func (m *MemoryAPI) SaveSalaryTimeContext(ctx context.Context, time api.EmployeeSalaryTime) (*api.SaveSalaryTimeResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return m.Store.SaveSalaryTime(time)
+}
+
+//SaveWorkingTimeContext saves the working time in the store
+func (m *MemoryAPI) SaveWorkingTimeContext(ctx context.Context, time api.EmployeeWorkingTime) (*api.SaveWorkingTimeResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return m.Store.SaveWorkingTime(time)
+}
+
+//SaveProjectTimeContext saves the project time in the store
+func (m *MemoryAPI) SaveProjectTimeContext(ctx context.Context, time api.EmployeeProjectTime) (*api.SaveProjectTimeResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return m.Store.SaveProjectTime(time)
+}
@@ -0,0 +1,269 @@
+package qbistest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+//Redacted replaces passwords and session cookies in recorded fixtures
+const Redacted = "REDACTED"
+
+//ignoredQueryParameters are not compared when matching requests to fixtures
+//("_" is a timestamp added to every GET to avoid caching)
+var ignoredQueryParameters = []string{"_"}
+
+//Fixture is a recorded request and response
+type Fixture struct {
+	Request struct {
+		Method string      `json:"method"`
+		Path   string      `json:"path"`
+		Query  url.Values  `json:"query,omitempty"`
+		Header http.Header `json:"header,omitempty"`
+		Body   string      `json:"body,omitempty"`
+	} `json:"request"`
+	Response struct {
+		StatusCode int         `json:"statusCode"`
+		Header     http.Header `json:"header,omitempty"`
+		Body       string      `json:"body,omitempty"`
+	} `json:"response"`
+}
+
+//Decode decodes the json body of the recorded response into v,
+//eg. to reproduce a decoding problem with a captured TimesheetData
+func (f *Fixture) Decode(v interface{}) error {
+	return json.Unmarshal([]byte(f.Response.Body), v)
+}
+
+//matches returns true if the fixture was recorded for a request like req
+func (f *Fixture) matches(req *http.Request) bool {
+	if f.Request.Method != req.Method || f.Request.Path != req.URL.Path {
+		return false
+	}
+	return queryString(f.Request.Query) == queryString(req.URL.Query())
+}
+
+//queryString encodes the query without the ignored parameters
+func queryString(query url.Values) string {
+	q := url.Values{}
+	for k, v := range query {
+		q[k] = v
+	}
+	for _, k := range ignoredQueryParameters {
+		q.Del(k)
+	}
+	return q.Encode()
+}
+
+//Recorder is a http.RoundTripper that saves every request and response as a Fixture in a directory,
+//with passwords and session cookies scrubbed. Use it with api.Client.WithHTTPClient or qbis.WithHTTPClient:
+//
+//	client := api.New(url).WithHTTPClient(&http.Client{Transport: qbistest.NewRecorder("testdata/week", nil)})
+type Recorder struct {
+	// Scrub is called with every fixture before it is written, to remove anything else that should not be saved
+	Scrub func(f *Fixture)
+
+	transport http.RoundTripper
+	dir       string
+
+	mu sync.Mutex
+	n  int
+}
+
+//NewRecorder returns a Recorder writing fixtures to dir, sending the requests with transport.
+//If transport is nil http.DefaultTransport is used.
+func NewRecorder(dir string, transport http.RoundTripper) *Recorder {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	return &Recorder{transport: transport, dir: dir}
+}
+
+//RoundTrip sends the request and records it together with the response
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil {
+		var err error
+		reqBody, err = ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = ioutil.NopCloser(bytes.NewReader(reqBody))
+	}
+
+	resp, err := r.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(respBody))
+
+	f := &Fixture{}
+	f.Request.Method = req.Method
+	f.Request.Path = req.URL.Path
+	f.Request.Query = req.URL.Query()
+	f.Request.Header = req.Header.Clone()
+	f.Request.Body = string(reqBody)
+	f.Response.StatusCode = resp.StatusCode
+	f.Response.Header = resp.Header.Clone()
+	f.Response.Body = string(respBody)
+
+	scrub(f)
+	if r.Scrub != nil {
+		r.Scrub(f)
+	}
+
+	err = r.write(f)
+	if err != nil {
+		return nil, fmt.Errorf("unable to record fixture: %v", err)
+	}
+	return resp, nil
+}
+
+//write saves the fixture in the next numbered file
+func (r *Recorder) write(f *Fixture) error {
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	err = os.MkdirAll(r.dir, 0700)
+	if err != nil {
+		return err
+	}
+	r.n++
+	name := fmt.Sprintf("%04d-%s-%s.json", r.n, f.Request.Method, strings.Replace(strings.Trim(f.Request.Path, "/"), "/", "_", -1))
+	return ioutil.WriteFile(filepath.Join(r.dir, name), data, 0600)
+}
+
+//scrub removes passwords and session cookies from the fixture
+func scrub(f *Fixture) {
+	if f.Request.Header.Get("Cookie") != "" {
+		cookies := make([]string, 0)
+		for _, c := range (&http.Request{Header: f.Request.Header}).Cookies() {
+			cookies = append(cookies, c.Name+"="+Redacted)
+		}
+		f.Request.Header.Set("Cookie", strings.Join(cookies, "; "))
+	}
+	f.Request.Header.Del("Authorization")
+
+	if strings.HasPrefix(f.Request.Header.Get("Content-Type"), "application/x-www-form-urlencoded") {
+		form, err := url.ParseQuery(f.Request.Body)
+		if err == nil && form.Get("Password") != "" {
+			form.Set("Password", Redacted)
+			f.Request.Body = form.Encode()
+		}
+	}
+
+	setCookies := f.Response.Header["Set-Cookie"]
+	for i, line := range setCookies {
+		c, err := http.ParseSetCookie(line)
+		if err != nil {
+			setCookies[i] = Redacted
+			continue
+		}
+		c.Value = Redacted
+		setCookies[i] = c.String()
+	}
+}
+
+//Replayer is a http.RoundTripper that answers requests with fixtures saved by a Recorder, without any network.
+//Requests are matched on method, path and query. Fixtures are used in the order they were recorded,
+//once they have all been used the last matching fixture is repeated.
+type Replayer struct {
+	mu       sync.Mutex
+	fixtures []*Fixture
+	used     []bool
+}
+
+//NewReplayer loads the fixtures in dir
+func NewReplayer(dir string) (*Replayer, error) {
+	fixtures, err := LoadFixtures(dir)
+	if err != nil {
+		return nil, err
+	}
+	return &Replayer{fixtures: fixtures, used: make([]bool, len(fixtures))}, nil
+}
+
+//LoadFixtures reads the fixtures in dir in the order they were recorded
+func LoadFixtures(dir string) ([]*Fixture, error) {
+	names, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+
+	fixtures := make([]*Fixture, 0, len(names))
+	for _, name := range names {
+		data, err := ioutil.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+		f := &Fixture{}
+		err = json.Unmarshal(data, f)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse fixture %s: %v", name, err)
+		}
+		fixtures = append(fixtures, f)
+	}
+	return fixtures, nil
+}
+
+//RoundTrip answers the request with the matching fixture
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	found := -1
+	for i, f := range r.fixtures {
+		if !f.matches(req) {
+			continue
+		}
+		found = i
+		if !r.used[i] {
+			break
+		}
+	}
+	if found < 0 {
+		return nil, fmt.Errorf("no fixture recorded for %s %s", req.Method, req.URL.RequestURI())
+	}
+	r.used[found] = true
+	f := r.fixtures[found]
+
+	header := f.Response.Header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", f.Response.StatusCode, http.StatusText(f.Response.StatusCode)),
+		StatusCode:    f.Response.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(strings.NewReader(f.Response.Body)),
+		ContentLength: int64(len(f.Response.Body)),
+		Request:       req,
+	}, nil
+}
@@ -0,0 +1,83 @@
+package qbistest_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/flipb/qbis-time/pkg/qbis/api"
+	"github.com/flipb/qbis-time/pkg/qbis/qbistest"
+)
+
+func TestRecorderScrubsSecretsAndReplays(t *testing.T) {
+	// a password that does not show up in the pages by chance
+	store := qbistest.NewStore()
+	store.Password = "s3cret-Pa55"
+	srv := qbistest.NewServerWithStore(store)
+	dir := t.TempDir()
+	employee := strconv.Itoa(qbistest.DefaultEmployeeID)
+	sunday := monday.AddDate(0, 0, 6)
+
+	recording := api.New(srv.URL).WithHTTPClient(&http.Client{Transport: qbistest.NewRecorder(dir, nil)})
+	err := recording.Login(store.Company, store.User, store.Password)
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	recorded, err := recording.GetTimesheet(employee, monday, sunday)
+	if err != nil {
+		t.Fatalf("GetTimesheet: %v", err)
+	}
+	session, err := recording.Session()
+	if err != nil {
+		t.Fatalf("Session: %v", err)
+	}
+	srv.Close()
+
+	secrets := []string{store.Password}
+	for _, c := range session.Cookies {
+		if c.Name == "ASP.NET_SessionId" {
+			secrets = append(secrets, c.Value)
+		}
+	}
+	if len(secrets) < 2 {
+		t.Fatal("the login did not set a session cookie")
+	}
+
+	names, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil || len(names) == 0 {
+		t.Fatalf("no fixtures recorded: %v", err)
+	}
+	for _, name := range names {
+		data, err := ioutil.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, secret := range secrets {
+			if strings.Contains(string(data), secret) {
+				t.Errorf("%s contains the secret %q", filepath.Base(name), secret)
+			}
+		}
+	}
+
+	// replay without the server, with any password
+	replayer, err := qbistest.NewReplayer(dir)
+	if err != nil {
+		t.Fatalf("NewReplayer: %v", err)
+	}
+	replaying := api.New(srv.URL).WithHTTPClient(&http.Client{Transport: replayer})
+	err = replaying.Login(store.Company, store.User, "not the password")
+	if err != nil {
+		t.Fatalf("Login from fixtures: %v", err)
+	}
+	replayed, err := replaying.GetTimesheet(employee, monday, sunday)
+	if err != nil {
+		t.Fatalf("GetTimesheet from fixtures: %v", err)
+	}
+	if !reflect.DeepEqual(recorded, replayed) {
+		t.Error("replayed timesheet differs from the recorded one")
+	}
+}
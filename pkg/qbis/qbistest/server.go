@@ -0,0 +1,358 @@
+//Package qbistest provides an in-process fake Qbis for testing code built on the qbis and api packages
+//without talking to the real service.
+package qbistest
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/flipb/qbis-time/pkg/qbis/api"
+)
+
+//sessionCookie is the name of the cookie holding the session ID
+const sessionCookie = "ASP.NET_SessionId"
+
+//Server is a fake Qbis implementing the endpoints used by the api package on top of a Store
+type Server struct {
+	*httptest.Server
+	Store *Store
+
+	mu       sync.Mutex
+	sessions map[string]bool
+}
+
+//NewServer starts a fake Qbis with a new Store. Close it when done.
+func NewServer() *Server {
+	return NewServerWithStore(NewStore())
+}
+
+//NewServerWithStore starts a fake Qbis serving the store. Close it when done.
+func NewServerWithStore(store *Store) *Server {
+	s := &Server{
+		Store:    store,
+		sessions: make(map[string]bool),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/Login/Login", s.handleLoginPage)
+	mux.HandleFunc("/Login/Login/Authenticate", s.handleAuthenticate)
+	mux.HandleFunc("/Time/TimeOverview", s.authenticated(false, s.handleTimeOverview))
+	mux.HandleFunc("/Time/Timesheet/GetTimeSheetData", s.authenticated(true, s.handleTimesheet))
+	mux.HandleFunc("/Time/TimesheetProjectTime/GetCustomerProjectDropDown", s.authenticated(true, s.handleProjects))
+	mux.HandleFunc("/Time/TimesheetProjectTime/GetActivityDropDown", s.authenticated(true, s.handleProjectActivityList))
+	mux.HandleFunc("/Time/TimesheetProjectTime/GetActivityInformation", s.authenticated(true, s.handleProjectActivity))
+	mux.HandleFunc("/Time/TimesheetProjectTime/GetActivityOverview", s.authenticated(true, s.handleActivityOverview))
+	mux.HandleFunc("/Time/TimesheetSalaryTime/GetActivityInformation", s.authenticated(true, s.handleSalaryActivity))
+	mux.HandleFunc("/Time/TimesheetSalaryTime/GetActivityOverview", s.authenticated(true, s.handleActivityOverview))
+	mux.HandleFunc("/Time/TimesheetProjectTime/SaveProjectTime", s.authenticated(true, s.handleSaveProjectTime))
+	mux.HandleFunc("/Time/TimesheetSalaryTime/SaveSalaryTime", s.authenticated(true, s.handleSaveSalaryTime))
+	mux.HandleFunc("/Time/TimesheetWorkingTime/SaveWorkingTime", s.authenticated(true, s.handleSaveWorkingTime))
+
+	s.Server = httptest.NewServer(mux)
+	return s
+}
+
+//ExpireSessions logs out every client, as if their sessions had timed out
+func (s *Server) ExpireSessions() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sessions = make(map[string]bool)
+}
+
+//authenticated wraps handlers that require a session. Like Qbis, requests without a session are
+//sent to the login page, except for json endpoints which get the login page html right away.
+func (s *Server) authenticated(jsonEndpoint bool, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		cookie, err := r.Cookie(sessionCookie)
+		s.mu.Lock()
+		ok := err == nil && s.sessions[cookie.Value]
+		s.mu.Unlock()
+
+		if !ok {
+			if jsonEndpoint && r.Method == http.MethodPost {
+				s.writeLoginPage(w, "")
+				return
+			}
+			http.Redirect(w, r, "/Login/Login?ReturnUrl="+url.QueryEscape(r.URL.RequestURI()), http.StatusFound)
+			return
+		}
+		handler(w, r)
+	}
+}
+
+func (s *Server) handleLoginPage(w http.ResponseWriter, r *http.Request) {
+	s.writeLoginPage(w, r.URL.Query().Get("message"))
+}
+
+func (s *Server) writeLoginPage(w http.ResponseWriter, message string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	errorClass := "validation-summary-valid"
+	if message != "" {
+		errorClass = "validation-summary-errors"
+	}
+	fmt.Fprintf(w, `<!DOCTYPE html>
+<html>
+<head><title>Qbis - Login</title></head>
+<body>
+<form action="/Login/Login/Authenticate" method="post">
+<div class="%s"><ul><li>%s</li></ul></div>
+<div class="form-group"><label for="Company">Company</label><input id="Company" name="Company" type="text"></div>
+<div class="form-group"><label for="Username">User name</label><input id="Username" name="Username" type="text"></div>
+<div class="form-group"><label for="Password">Password</label><input id="Password" name="Password" type="password"></div>
+<input type="submit" name="Authenticate" value="Log in">
+</form>
+</body>
+</html>`, errorClass, html.EscapeString(message))
+}
+
+func (s *Server) handleAuthenticate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	err := r.ParseForm()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ok, message := s.Store.Login(r.PostForm.Get("Company"), r.PostForm.Get("Username"), r.PostForm.Get("Password"))
+	if !ok {
+		http.Redirect(w, r, "/Login/Login?message="+url.QueryEscape(message), http.StatusFound)
+		return
+	}
+
+	id := newSessionID()
+	s.mu.Lock()
+	s.sessions[id] = true
+	s.mu.Unlock()
+
+	http.SetCookie(w, &http.Cookie{Name: sessionCookie, Value: id, Path: "/", HttpOnly: true})
+	if r.PostForm.Get("RememberMe") == "true" {
+		http.SetCookie(w, &http.Cookie{Name: "QbisRememberMe", Value: id, Path: "/", HttpOnly: true, Expires: time.Now().AddDate(0, 1, 0)})
+	}
+	http.Redirect(w, r, "/Time/TimeOverview", http.StatusFound)
+}
+
+func (s *Server) handleTimeOverview(w http.ResponseWriter, r *http.Request) {
+	info := s.Store.LoginInfo()
+
+	permissions := make([]string, 0, len(info.Permissions))
+	for k, v := range info.Permissions {
+		permissions = append(permissions, fmt.Sprintf(",\n        %s: %t", k, v))
+	}
+	sort.Strings(permissions)
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprintf(w, `<!DOCTYPE html>
+<html>
+<head><title>Qbis - Time overview</title></head>
+<body>
+<div id="timeOverview"></div>
+<script type="text/javascript">
+    var currentLogin = {
+        currentUser: '%s',
+        userName: '%s',
+        companyName: '%s',
+        language: '%s'%s
+    };
+</script>
+</body>
+</html>`, info.EmployeeID, jsString(info.UserName), jsString(info.Company), jsString(info.Language), strings.Join(permissions, ""))
+}
+
+func (s *Server) handleTimesheet(w http.ResponseWriter, r *http.Request) {
+	from, to, err := dateSpan(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	writeJSON(w)(s.Store.Timesheet(r.URL.Query().Get("employeeId"), from, to))
+}
+
+func (s *Server) handleProjects(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w)(s.Store.ProjectCompanies(r.URL.Query().Get("employeeId")))
+}
+
+func (s *Server) handleProjectActivityList(w http.ResponseWriter, r *http.Request) {
+	projectID, err := strconv.Atoi(r.URL.Query().Get("projectID"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	writeJSON(w)(s.Store.ProjectActivityList(r.URL.Query().Get("employeeId"), projectID))
+}
+
+func (s *Server) handleProjectActivity(w http.ResponseWriter, r *http.Request) {
+	activityID, from, err := activityQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	writeJSON(w)(s.Store.ProjectActivity(r.URL.Query().Get("employeeId"), activityID, from))
+}
+
+func (s *Server) handleSalaryActivity(w http.ResponseWriter, r *http.Request) {
+	activityID, from, err := activityQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	writeJSON(w)(s.Store.SalaryActivity(r.URL.Query().Get("employeeId"), activityID, from))
+}
+
+//handleActivityOverview serves the overview of both project and salary activities.
+//The store does not keep budgets, so all of them are zero.
+func (s *Server) handleActivityOverview(w http.ResponseWriter, r *http.Request) {
+	overview := api.ActivityOverviewBase{DisplayName: r.URL.Query().Get("activityId")}
+	for _, id := range []string{"activityHours", "allocatedHours", "registeredHours"} {
+		overview.Properties = append(overview.Properties, struct {
+			TextIdentifier string `json:"TextIdentifier"`
+			Value          string `json:"Value"`
+			UnitIdentifier string `json:"UnitIdentifier"`
+		}{id, "0", "unitHours"})
+	}
+	writeJSON(w)(overview, nil)
+}
+
+func (s *Server) handleSaveProjectTime(w http.ResponseWriter, r *http.Request) {
+	var payload api.EmployeeProjectTime
+	if !readJSON(w, r, &payload) {
+		return
+	}
+	writeJSON(w)(s.Store.SaveProjectTime(payload))
+}
+
+func (s *Server) handleSaveSalaryTime(w http.ResponseWriter, r *http.Request) {
+	var payload api.EmployeeSalaryTime
+	if !readJSON(w, r, &payload) {
+		return
+	}
+	writeJSON(w)(s.Store.SaveSalaryTime(payload))
+}
+
+func (s *Server) handleSaveWorkingTime(w http.ResponseWriter, r *http.Request) {
+	var payload api.EmployeeWorkingTime
+	if !readJSON(w, r, &payload) {
+		return
+	}
+	writeJSON(w)(s.Store.SaveWorkingTime(payload))
+}
+
+//writeJSON returns a function writing the result of a store call as json, or the error as a 500.
+//Dates are written as "/Date(1520809200000)/" like Qbis does.
+func writeJSON(w http.ResponseWriter) func(v interface{}, err error) {
+	return func(v interface{}, err error) {
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		data, err := json.Marshal(v)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		var generic interface{}
+		err = json.Unmarshal(data, &generic)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		json.NewEncoder(w).Encode(qbisDates(generic))
+	}
+}
+
+//qbisDates rewrites the ISO 8601 dates in the decoded json v, the properties with names ending with "Date",
+//to the "/Date(1520809200000)/" form Qbis sends. Empty dates are left as they are.
+func qbisDates(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			if s, ok := value.(string); ok && s != "" && strings.HasSuffix(key, "Date") {
+				t, err := api.ParseDate(s)
+				if err == nil {
+					v[key] = api.TimeToDateString(t)
+				}
+				continue
+			}
+			v[key] = qbisDates(value)
+		}
+	case []interface{}:
+		for i := range v {
+			v[i] = qbisDates(v[i])
+		}
+	}
+	return v
+}
+
+//readJSON decodes the request body into v, writing an error response and returning false if it can't
+func readJSON(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return false
+	}
+	err := json.NewDecoder(r.Body).Decode(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+//dateSpan returns the fromDate and toDate query parameters
+func dateSpan(r *http.Request) (from time.Time, to time.Time, err error) {
+	from, err = api.ParseDate(r.URL.Query().Get("fromDate"))
+	if err != nil {
+		return from, to, fmt.Errorf("invalid fromDate: %v", err)
+	}
+	to, err = api.ParseDate(r.URL.Query().Get("toDate"))
+	if err != nil {
+		return from, to, fmt.Errorf("invalid toDate: %v", err)
+	}
+	return from, to, nil
+}
+
+//activityQuery returns the activityId and fromDate query parameters
+func activityQuery(r *http.Request) (int, time.Time, error) {
+	activityID, err := strconv.Atoi(r.URL.Query().Get("activityId"))
+	if err != nil {
+		return 0, time.Time{}, fmt.Errorf("invalid activityId: %v", err)
+	}
+	from, _, err := dateSpan(r)
+	return activityID, from, err
+}
+
+//jsString escapes s for use in a single quoted javascript string
+func jsString(s string) string {
+	b, _ := json.Marshal(s)
+	quoted := string(b[1 : len(b)-1])
+	out := make([]rune, 0, len(quoted))
+	for _, r := range quoted {
+		if r == '\'' {
+			out = append(out, '\\')
+		}
+		out = append(out, r)
+	}
+	return string(out)
+}
+
+func newSessionID() string {
+	b := make([]byte, 16)
+	_, err := rand.Read(b)
+	if err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,264 @@
+package qbistest_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/flipb/qbis-time/pkg/qbis"
+	"github.com/flipb/qbis-time/pkg/qbis/api"
+	"github.com/flipb/qbis-time/pkg/qbis/qbistest"
+)
+
+//monday is the start of the week used by the tests, in the time zone of the store
+var monday = time.Date(2024, time.March, 4, 0, 0, 0, 0, time.Local)
+
+func TestServerRoundTrip(t *testing.T) {
+	srv := qbistest.NewServer()
+	defer srv.Close()
+
+	client := api.New(srv.URL)
+	err := client.Login(qbistest.DefaultCompany, qbistest.DefaultUser, qbistest.DefaultPassword)
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	roundTrip(t, client)
+}
+
+//responseRecorder keeps the bodies of the responses from paths ending with suffix
+type responseRecorder struct {
+	suffix string
+	bodies [][]byte
+}
+
+func (r *responseRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := http.DefaultTransport.RoundTrip(req)
+	if err != nil || !strings.HasSuffix(req.URL.Path, r.suffix) {
+		return resp, err
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	r.bodies = append(r.bodies, body)
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	return resp, nil
+}
+
+func TestServerSendsQbisDates(t *testing.T) {
+	srv := qbistest.NewServer()
+	defer srv.Close()
+	recorder := &responseRecorder{suffix: "/GetTimeSheetData"}
+
+	client := api.New(srv.URL).WithHTTPClient(&http.Client{Transport: recorder})
+	err := client.Login(qbistest.DefaultCompany, qbistest.DefaultUser, qbistest.DefaultPassword)
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	sheet, err := client.GetTimesheet(strconv.Itoa(qbistest.DefaultEmployeeID), monday, monday.AddDate(0, 0, 6))
+	if err != nil {
+		t.Fatalf("GetTimesheet: %v", err)
+	}
+	if len(recorder.bodies) != 1 {
+		t.Fatalf("got %d timesheet responses, want 1", len(recorder.bodies))
+	}
+	want := `"DayDate":"` + api.TimeToDateString(monday) + `"`
+	if body := recorder.bodies[0]; !bytes.Contains(body, []byte(want)) || bytes.Contains(body, []byte(`T00:00:00`)) {
+		t.Errorf("timesheet does not have dates like %s:\n%s", want, body)
+	}
+	if !sheet.DaySettings[0].DayDate.Equal(monday) {
+		t.Errorf("monday is %v, want %v", sheet.DaySettings[0].DayDate.Time, monday)
+	}
+}
+
+func TestSaveSalaryTimeStoresWorkingTime(t *testing.T) {
+	ctx := context.Background()
+	backend := qbistest.NewMemoryAPI(qbistest.NewStore())
+	employee := strconv.Itoa(qbistest.DefaultEmployeeID)
+	sunday := monday.AddDate(0, 0, 6)
+
+	sheet, err := backend.GetTimesheetContext(ctx, employee, monday, sunday)
+	if err != nil {
+		t.Fatalf("GetTimesheet: %v", err)
+	}
+	days := make([]api.WorkingTimeBase, 0)
+	for _, day := range sheet.WorkingTimeDays {
+		days = append(days, day.WorkingTimeBase)
+	}
+	days[1].Arrive, days[1].Leave = 9*60, 15*60
+	salary, err := backend.SaveSalaryTimeContext(ctx, api.EmployeeSalaryTime{
+		EmployeeID: employee, FromDate: api.NewDate(monday), ToDate: api.NewDate(sunday), WorkingTime: days,
+	})
+	if err != nil || !salary.WasSaved {
+		t.Fatalf("SaveSalaryTime: %+v, %v", salary, err)
+	}
+
+	saved, err := backend.GetTimesheetContext(ctx, employee, monday, sunday)
+	if err != nil {
+		t.Fatalf("GetTimesheet after saving: %v", err)
+	}
+	if got := saved.WorkingTimeDays[1]; got.Arrive != 9*60 || got.Leave != 15*60 || got.Total != 6*60 {
+		t.Errorf("working time = %d-%d total %d, want 540-900 total 360", got.Arrive, got.Leave, got.Total)
+	}
+}
+
+func TestServerRejectsLogin(t *testing.T) {
+	tests := []struct {
+		name     string
+		company  string
+		password string
+		locked   bool
+		want     error
+	}{
+		{"wrong password", qbistest.DefaultCompany, "wrong", false, api.ErrInvalidCredentials},
+		{"wrong company", "other company", qbistest.DefaultPassword, false, api.ErrUnknownCompany},
+		{"locked account", qbistest.DefaultCompany, qbistest.DefaultPassword, true, api.ErrLockedAccount},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store := qbistest.NewStore()
+			store.Locked = test.locked
+			srv := qbistest.NewServerWithStore(store)
+			defer srv.Close()
+
+			err := api.New(srv.URL).Login(test.company, qbistest.DefaultUser, test.password)
+			var authErr *api.AuthError
+			if !errors.As(err, &authErr) {
+				t.Fatalf("expected an *api.AuthError, got %v", err)
+			}
+			if !errors.Is(err, test.want) {
+				t.Errorf("got %v, want %v", err, test.want)
+			}
+			if authErr.Message == "" {
+				t.Error("the message of the login page was not kept")
+			}
+		})
+	}
+}
+
+func TestServerExpiredSession(t *testing.T) {
+	srv := qbistest.NewServer()
+	defer srv.Close()
+
+	client := api.New(srv.URL)
+	err := client.Login(qbistest.DefaultCompany, qbistest.DefaultUser, qbistest.DefaultPassword)
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	srv.ExpireSessions()
+
+	// the client logs in again with the credentials of the last login
+	_, err = client.GetTimesheet(strconv.Itoa(qbistest.DefaultEmployeeID), monday, monday.AddDate(0, 0, 6))
+	if err != nil {
+		t.Fatalf("GetTimesheet after the session expired: %v", err)
+	}
+}
+
+func TestMemoryAPIRoundTrip(t *testing.T) {
+	roundTrip(t, qbistest.NewMemoryAPI(qbistest.NewStore()))
+}
+
+//roundTrip reads the week, saves working, salary and project time and checks that they were stored
+func roundTrip(t *testing.T, backend qbis.API) {
+	t.Helper()
+	ctx := context.Background()
+	sunday := monday.AddDate(0, 0, 6)
+
+	info, err := backend.GetLoginInfoContext(ctx)
+	if err != nil {
+		t.Fatalf("GetLoginInfo: %v", err)
+	}
+	if info.EmployeeID != strconv.Itoa(qbistest.DefaultEmployeeID) {
+		t.Fatalf("employee ID = %q, want %d", info.EmployeeID, qbistest.DefaultEmployeeID)
+	}
+	employee := info.EmployeeID
+
+	sheet, err := backend.GetTimesheetContext(ctx, employee, monday, sunday)
+	if err != nil {
+		t.Fatalf("GetTimesheet: %v", err)
+	}
+	if len(sheet.WorkingTimeDays) != 7 || len(sheet.DaySettings) != 7 {
+		t.Fatalf("got %d working days and %d day settings, want 7", len(sheet.WorkingTimeDays), len(sheet.DaySettings))
+	}
+
+	// working time
+	days := make([]api.WorkingTimeBase, 0)
+	for _, day := range sheet.WorkingTimeDays {
+		days = append(days, day.WorkingTimeBase)
+	}
+	days[0].Arrive, days[0].Leave, days[0].Lunch = 8*60, 17*60, 60
+	_, err = backend.SaveWorkingTimeContext(ctx, api.EmployeeWorkingTime{
+		EmployeeID: employee, FromDate: api.NewDate(monday), ToDate: api.NewDate(sunday), Days: days,
+	})
+	if err != nil {
+		t.Fatalf("SaveWorkingTime: %v", err)
+	}
+
+	// salary time, on an activity that is not in the week yet
+	sick, err := backend.GetSalaryActivityContext(ctx, employee, qbistest.SickLeaveActivityID, monday, sunday)
+	if err != nil {
+		t.Fatalf("GetSalaryActivity: %v", err)
+	}
+	sick.Days[0].DayMinutes = -60
+	salary, err := backend.SaveSalaryTimeContext(ctx, api.EmployeeSalaryTime{
+		EmployeeID: employee, FromDate: api.NewDate(monday), ToDate: api.NewDate(sunday),
+		SalaryTime: []api.SalaryTimeBase{sick.SalaryTimeBase}, WorkingTime: days,
+	})
+	if err != nil || !salary.WasSaved {
+		t.Fatalf("SaveSalaryTime: %+v, %v", salary, err)
+	}
+
+	// project time
+	development, err := backend.GetProjectActivityContext(ctx, employee, qbistest.DevelopmentActivityID, monday, sunday)
+	if err != nil {
+		t.Fatalf("GetProjectActivity: %v", err)
+	}
+	development.Days[0].DayMinutes = 240
+	development.Days[0].InternalNotes = "round trip"
+	project, err := backend.SaveProjectTimeContext(ctx, api.EmployeeProjectTime{
+		EmployeeID: employee, FromDate: api.NewDate(monday), ToDate: api.NewDate(sunday),
+		List: []api.ProjectTime{*development},
+	})
+	if err != nil || !project.WasSaved {
+		t.Fatalf("SaveProjectTime: %+v, %v", project, err)
+	}
+
+	saved, err := backend.GetTimesheetContext(ctx, employee, monday, sunday)
+	if err != nil {
+		t.Fatalf("GetTimesheet after saving: %v", err)
+	}
+	if got := saved.WorkingTimeDays[0]; got.Arrive != 8*60 || got.Leave != 17*60 || got.Lunch != 60 || got.Total != 8*60 {
+		t.Errorf("working time = %d-%d lunch %d total %d, want 480-1020 lunch 60 total 480", got.Arrive, got.Leave, got.Lunch, got.Total)
+	}
+	if got := salaryMinutes(saved, qbistest.SickLeaveActivityID); got != -60 {
+		t.Errorf("sick leave = %d minutes, want -60", got)
+	}
+	found := false
+	for _, row := range saved.ListOfProjectTime {
+		if row.ActivityID == qbistest.DevelopmentActivityID {
+			found = true
+			if row.Days[0].DayMinutes != 240 || row.Days[0].InternalNotes != "round trip" {
+				t.Errorf("development = %d minutes %q, want 240 minutes \"round trip\"", row.Days[0].DayMinutes, row.Days[0].InternalNotes)
+			}
+		}
+	}
+	if !found {
+		t.Error("development activity was not saved")
+	}
+}
+
+func salaryMinutes(sheet *api.TimesheetData, activityID int) api.Minutes {
+	for _, row := range sheet.ListOfSalaryTime {
+		if row.ActivityID == activityID {
+			return row.Days[0].DayMinutes
+		}
+	}
+	return 0
+}
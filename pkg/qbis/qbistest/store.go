@@ -0,0 +1,534 @@
+package qbistest
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/flipb/qbis-time/pkg/qbis/api"
+)
+
+//Default credentials and employee of a new Store
+const (
+	DefaultCompany    = "company"
+	DefaultUser       = "user"
+	DefaultPassword   = "password"
+	DefaultEmployeeID = 1234
+)
+
+//Activity IDs of the salary activities in a new Store
+const (
+	CompTimeActivityID  = 1 // the default salary activity
+	SickLeaveActivityID = 10
+	OvertimeActivityID  = 11
+)
+
+//Project and activity IDs of the projects in a new Store
+const (
+	ProjectID                = 100
+	DevelopmentActivityID    = 1001
+	MeetingsActivityID       = 1002
+	InternalProjectID        = 200
+	AdministrationActivityID = 2001
+)
+
+//Store is an in-memory Qbis with the timesheets of a single employee.
+//The exported fields should be set up before the store is used.
+type Store struct {
+	Company    string
+	User       string
+	Password   string
+	EmployeeID int
+	UserName   string
+	Language   string
+	// Permissions are the flags of the currentLogin block on the time overview
+	Permissions map[string]bool
+	// Locked makes every login fail with a locked account message
+	Locked bool
+	// Location is the time zone of the employee, used to find the dates of the week
+	Location *time.Location
+	// ScheduledMinutes is the scheduled working time monday to friday
+	ScheduledMinutes int
+
+	// SalaryActivities are the salary activities available to the employee, the first one is the default
+	SalaryActivities []api.SalaryTimeBase
+	// Projects are the companies and projects available to the employee
+	Projects []api.ProjectCompany
+	// ProjectActivities are the activities of the projects, by project ID
+	ProjectActivities map[int][]api.ProjectActivityListItem
+
+	mu    sync.Mutex
+	weeks map[string]*api.TimesheetData
+}
+
+//NewStore returns a store with a default employee, salary activities and projects
+func NewStore() *Store {
+	s := &Store{
+		Company:          DefaultCompany,
+		User:             DefaultUser,
+		Password:         DefaultPassword,
+		EmployeeID:       DefaultEmployeeID,
+		UserName:         "Test User",
+		Language:         "en",
+		Permissions:      map[string]bool{"isAllowedToRegisterWorkingTimeFromWeb": true},
+		Location:         time.Local,
+		ScheduledMinutes: 8 * 60,
+		weeks:            make(map[string]*api.TimesheetData),
+	}
+
+	s.SalaryActivities = []api.SalaryTimeBase{
+		salaryActivity(CompTimeActivityID, "Comp time", 3, true, true),
+		salaryActivity(SickLeaveActivityID, "Sick leave", 0, true, false),
+		salaryActivity(OvertimeActivityID, "Overtime x1.5", 0, false, true),
+	}
+	s.SalaryActivities[0].IsDefault = true
+
+	s.Projects = []api.ProjectCompany{
+		{
+			CompanyID:   1,
+			CompanyName: "Customer AB",
+			Projects:    []api.Project{{Code: "P100", ID: ProjectID, Name: "Customer project"}},
+		},
+		{
+			CompanyID:   2,
+			CompanyName: "Our Company AB",
+			Projects:    []api.Project{{Code: "P200", ID: InternalProjectID, Name: "Internal"}},
+		},
+	}
+	s.ProjectActivities = map[int][]api.ProjectActivityListItem{
+		ProjectID: {
+			{Factor: "1", ID: DevelopmentActivityID, Name: "Development"},
+			{Factor: "1", ID: MeetingsActivityID, Name: "Meetings"},
+		},
+		InternalProjectID: {
+			{Factor: "1", ID: AdministrationActivityID, Name: "Administration"},
+		},
+	}
+
+	return s
+}
+
+func salaryActivity(id int, name string, activityType int, allowNegative bool, allowPositive bool) api.SalaryTimeBase {
+	a := api.SalaryTimeBase{
+		AllowNegative:    allowNegative,
+		AllowPositive:    allowPositive,
+		PresentationUnit: "h",
+		Type:             activityType,
+	}
+	a.ActivityActive = true
+	a.ActivityID = id
+	a.ActivityName = name
+	return a
+}
+
+//Login checks the credentials, returning a message to show on the login page if they are not accepted
+func (s *Store) Login(company string, user string, password string) (ok bool, message string) {
+	switch {
+	case company != s.Company:
+		return false, "Unknown company"
+	case s.Locked:
+		return false, "Your account is locked"
+	case user != s.User || password != s.Password:
+		return false, "Invalid user name or password"
+	}
+	return true, ""
+}
+
+//LoginInfo returns the profile of the employee
+func (s *Store) LoginInfo() *api.LoginInfo {
+	info := &api.LoginInfo{
+		EmployeeID:  strconv.Itoa(s.EmployeeID),
+		UserName:    s.UserName,
+		Company:     s.Company,
+		Language:    s.Language,
+		Permissions: make(map[string]bool),
+		Raw: map[string]interface{}{
+			"currentUser": strconv.Itoa(s.EmployeeID),
+			"userName":    s.UserName,
+			"companyName": s.Company,
+			"language":    s.Language,
+		},
+	}
+	for k, v := range s.Permissions {
+		info.Permissions[k] = v
+		info.Raw[k] = v
+	}
+	return info
+}
+
+//Timesheet returns a copy of the timesheet for the week starting at from, creating an empty week if needed
+func (s *Store) Timesheet(employeeID string, from time.Time, to time.Time) (*api.TimesheetData, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sheet, err := s.week(employeeID, from)
+	if err != nil {
+		return nil, err
+	}
+	var c api.TimesheetData
+	clone(sheet, &c)
+	return &c, nil
+}
+
+//SetTimesheet replaces the timesheet of the week starting at from, eg. to simulate edits in the web ui
+func (s *Store) SetTimesheet(from time.Time, sheet *api.TimesheetData) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var c api.TimesheetData
+	clone(sheet, &c)
+	s.weeks[s.weekKey(from)] = &c
+}
+
+//UpdateTimesheet calls update with the stored timesheet of the week starting at from so it can be changed in place
+func (s *Store) UpdateTimesheet(from time.Time, update func(sheet *api.TimesheetData)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sheet, err := s.week(strconv.Itoa(s.EmployeeID), from)
+	if err != nil {
+		return err
+	}
+	update(sheet)
+	return nil
+}
+
+//ProjectCompanies returns the companies and projects of the employee
+func (s *Store) ProjectCompanies(employeeID string) ([]api.ProjectCompany, error) {
+	if err := s.checkEmployee(employeeID); err != nil {
+		return nil, err
+	}
+	var c []api.ProjectCompany
+	clone(s.Projects, &c)
+	return c, nil
+}
+
+//ProjectActivityList returns the activities of the project
+func (s *Store) ProjectActivityList(employeeID string, projectID int) ([]api.ProjectActivityListItem, error) {
+	if err := s.checkEmployee(employeeID); err != nil {
+		return nil, err
+	}
+	activities, ok := s.ProjectActivities[projectID]
+	if !ok {
+		return nil, fmt.Errorf("unknown project %d", projectID)
+	}
+	var c []api.ProjectActivityListItem
+	clone(activities, &c)
+	return c, nil
+}
+
+//ProjectActivity returns an empty week row for the project activity
+func (s *Store) ProjectActivity(employeeID string, activityID int, from time.Time) (*api.ProjectTime, error) {
+	if err := s.checkEmployee(employeeID); err != nil {
+		return nil, err
+	}
+	for _, company := range s.Projects {
+		for _, project := range company.Projects {
+			for _, activity := range s.ProjectActivities[project.ID] {
+				if activity.ID != activityID {
+					continue
+				}
+				return s.projectTime(company, project, activity, from), nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("unknown project activity %d", activityID)
+}
+
+//SalaryActivity returns an empty week row for the salary activity
+func (s *Store) SalaryActivity(employeeID string, activityID int, from time.Time) (*api.SalaryTime, error) {
+	if err := s.checkEmployee(employeeID); err != nil {
+		return nil, err
+	}
+	for _, activity := range s.SalaryActivities {
+		if activity.ActivityID != activityID {
+			continue
+		}
+		return s.salaryTime(activity, from), nil
+	}
+	return nil, fmt.Errorf("unknown salary activity %d", activityID)
+}
+
+//SaveSalaryTime stores the salary time rows of the payload. Like Qbis, it also stores the working time that is sent
+//along with the salary time, when there is a day for every day of the week.
+func (s *Store) SaveSalaryTime(payload api.EmployeeSalaryTime) (*api.SaveSalaryTimeResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sheet, err := s.week(payload.EmployeeID, payload.FromDate.Time)
+	if err != nil {
+		return nil, err
+	}
+
+	if limits := salaryLimitErrors(payload.SalaryTime); len(limits) > 0 {
+		return &api.SaveSalaryTimeResponse{LimitErrorResults: limits, WasSaved: false}, nil
+	}
+
+	for _, row := range payload.SalaryTime {
+		var saved api.SalaryTime
+		for i := range sheet.ListOfSalaryTime {
+			if sheet.ListOfSalaryTime[i].ActivityID == row.ActivityID {
+				saved = sheet.ListOfSalaryTime[i]
+				sheet.ListOfSalaryTime = append(sheet.ListOfSalaryTime[:i], sheet.ListOfSalaryTime[i+1:]...)
+				break
+			}
+		}
+		clone(row, &saved.SalaryTimeBase)
+		saved.IsNewRow = false
+		sheet.ListOfSalaryTime = append(sheet.ListOfSalaryTime, saved)
+	}
+	if len(payload.WorkingTime) == len(sheet.WorkingTimeDays) {
+		saveWorkingTimeDays(sheet, payload.WorkingTime)
+	}
+
+	return &api.SaveSalaryTimeResponse{WasSaved: true}, nil
+}
+
+//salaryLimitErrors returns a LimitResult for every day with more minutes than the UpperLimit of the activity,
+//or less than the LowerLimit. Limits that are 0 are not checked.
+func salaryLimitErrors(rows []api.SalaryTimeBase) []api.LimitResult {
+	limits := make([]api.LimitResult, 0)
+	for _, row := range rows {
+		for _, day := range row.Days {
+			limit := 0
+			switch {
+			case row.UpperLimit != 0 && int(day.DayMinutes) > row.UpperLimit:
+				limit = row.UpperLimit
+			case row.LowerLimit != 0 && int(day.DayMinutes) < row.LowerLimit:
+				limit = row.LowerLimit
+			default:
+				continue
+			}
+			limits = append(limits, api.LimitResult{
+				ActivityID:   row.ActivityID,
+				ActivityName: row.ActivityName,
+				DayDate:      day.DayDate,
+				Limit:        api.Minutes(limit),
+				Value:        day.DayMinutes,
+				Message:      fmt.Sprintf("%d minutes is outside the limit of %d minutes", day.DayMinutes, limit),
+			})
+		}
+	}
+	return limits
+}
+
+//SaveWorkingTime stores the arrival, departure and lunch of the payload
+func (s *Store) SaveWorkingTime(payload api.EmployeeWorkingTime) (*api.SaveWorkingTimeResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sheet, err := s.week(payload.EmployeeID, payload.FromDate.Time)
+	if err != nil {
+		return nil, err
+	}
+	if len(payload.Days) != len(sheet.WorkingTimeDays) {
+		return &api.SaveWorkingTimeResponse{Saved: "unexpected number of days"}, nil
+	}
+	saveWorkingTimeDays(sheet, payload.Days)
+
+	return &api.SaveWorkingTimeResponse{}, nil
+}
+
+//saveWorkingTimeDays stores the arrival, departure and lunch of the days, one for every day of the week, and updates the summary
+func saveWorkingTimeDays(sheet *api.TimesheetData, days []api.WorkingTimeBase) {
+	worked := 0
+	for i, day := range days {
+		w := &sheet.WorkingTimeDays[i]
+		w.Arrive = day.Arrive
+		w.Leave = day.Leave
+		w.Lunch = day.Lunch
+		w.Total = day.Leave - day.Arrive - day.Lunch
+		if w.Total < 0 {
+			w.Total = 0
+		}
+		w.IsModified = false
+		w.IsSaved = true
+		worked += int(w.Total)
+	}
+	sheet.SummaryData.WorkedHours = float64(worked) / 60
+	sheet.SummaryData.WorkedTime = fmt.Sprintf("%d:%02d", worked/60, worked%60)
+}
+
+//SaveProjectTime stores the project time rows of the payload
+func (s *Store) SaveProjectTime(payload api.EmployeeProjectTime) (*api.SaveProjectTimeResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sheet, err := s.week(payload.EmployeeID, payload.FromDate.Time)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range payload.List {
+		var saved api.ProjectTime
+		clone(row, &saved)
+		saved.IsNewRow = false
+
+		replaced := false
+		for i := range sheet.ListOfProjectTime {
+			if sheet.ListOfProjectTime[i].ActivityID == row.ActivityID {
+				sheet.ListOfProjectTime[i] = saved
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			sheet.ListOfProjectTime = append(sheet.ListOfProjectTime, saved)
+		}
+	}
+
+	return &api.SaveProjectTimeResponse{WasSaved: true}, nil
+}
+
+//checkEmployee returns an error if the employee is not the one in the store
+func (s *Store) checkEmployee(employeeID string) error {
+	if employeeID != strconv.Itoa(s.EmployeeID) {
+		return fmt.Errorf("unknown employee %s", employeeID)
+	}
+	return nil
+}
+
+//weekKey returns the date the week starts as a string
+func (s *Store) weekKey(from time.Time) string {
+	return from.In(s.Location).Format("2006-01-02")
+}
+
+//week returns the stored week starting at from, creating it if it does not exist yet
+func (s *Store) week(employeeID string, from time.Time) (*api.TimesheetData, error) {
+	if err := s.checkEmployee(employeeID); err != nil {
+		return nil, err
+	}
+	from = from.In(s.Location)
+	if from.Weekday() != time.Monday {
+		return nil, fmt.Errorf("week must start on a monday, got %s", from.Weekday())
+	}
+	key := s.weekKey(from)
+	sheet, ok := s.weeks[key]
+	if !ok {
+		sheet = s.newWeek(from)
+		s.weeks[key] = sheet
+	}
+	return sheet, nil
+}
+
+//newWeek creates an empty week with the default salary activity
+func (s *Store) newWeek(from time.Time) *api.TimesheetData {
+	sheet := &api.TimesheetData{}
+	scheduled := 0
+
+	for i, date := range s.days(from) {
+		workingDay := i < 5
+		ds := api.DaySetting{
+			DayDate:       api.NewDate(date),
+			DayNameString: date.Weekday().String(),
+			HasSchedule:   workingDay,
+			IsWorkingDay:  workingDay,
+			LunchMaximum:  120,
+			MonthName:     date.Month().String(),
+		}
+		ds.IsAllowedToRegisterWorkingTimeFromWeb = true
+		ds.MySchedule.DayDate = ds.DayDate
+		if workingDay {
+			ds.MySchedule.TotalMinutes = api.Minutes(s.ScheduledMinutes)
+			scheduled += s.ScheduledMinutes
+		}
+		sheet.DaySettings = append(sheet.DaySettings, ds)
+
+		wt := api.WorkingTime{}
+		wt.DayDate = ds.DayDate
+		wt.DayName = ds.DayNameString
+		wt.HasSchedule = workingDay
+		wt.ScheduleDay = ds.MySchedule
+		if workingDay {
+			wt.ScheduledHours = s.ScheduledMinutes / 60
+		}
+		sheet.WorkingTimeDays = append(sheet.WorkingTimeDays, wt)
+	}
+
+	sheet.SummaryData.HasSchedule = true
+	sheet.SummaryData.ScheduledHours = float64(scheduled) / 60
+	sheet.SummaryData.ScheduledTime = fmt.Sprintf("%d:%02d", scheduled/60, scheduled%60)
+	sheet.TimeSettings.IsActive = true
+	sheet.TimeSettings.SalarytimeAccess = true
+	sheet.TimeSettings.WorkingtimeAccess = true
+	sheet.TimeSettings.ProjectactivityAccess = true
+
+	for i, activity := range s.SalaryActivities {
+		if i == 0 {
+			sheet.ListOfSalaryTime = append(sheet.ListOfSalaryTime, *s.salaryTime(activity, from))
+			continue
+		}
+		sheet.ListOfSalaryActivities = append(sheet.ListOfSalaryActivities, struct {
+			Key   int    `json:"Key"`
+			Value string `json:"Value"`
+		}{activity.ActivityID, activity.ActivityName})
+	}
+
+	return sheet
+}
+
+//days returns the dates of the week starting at from
+func (s *Store) days(from time.Time) []time.Time {
+	days := make([]time.Time, 7)
+	from = from.In(s.Location)
+	for i := range days {
+		days[i] = time.Date(from.Year(), from.Month(), from.Day()+i, 0, 0, 0, 0, s.Location)
+	}
+	return days
+}
+
+//salaryTime returns an empty week row for the salary activity
+func (s *Store) salaryTime(activity api.SalaryTimeBase, from time.Time) *api.SalaryTime {
+	row := &api.SalaryTime{}
+	clone(activity, &row.SalaryTimeBase)
+	row.EmployeeID = s.EmployeeID
+	row.IsNewRow = true
+	emptyDays(&row.Days)
+	for i, date := range s.days(from) {
+		row.Days[i].DayDate = api.NewDate(date)
+	}
+	return row
+}
+
+//projectTime returns an empty week row for the project activity
+func (s *Store) projectTime(company api.ProjectCompany, project api.Project, activity api.ProjectActivityListItem, from time.Time) *api.ProjectTime {
+	row := &api.ProjectTime{}
+	row.ActivityActive = true
+	row.ActivityID = activity.ID
+	row.ActivityName = activity.Name
+	row.EmployeeID = s.EmployeeID
+	row.Factor = 1
+	row.IsNewRow = true
+	row.CustomerName = company.CompanyName
+	row.CustomerFullName = company.CompanyName
+	row.ProjectName = project.Name
+	row.ProjectFullName = project.Code + " " + project.Name
+	row.CustomerProjectActivityName = company.CompanyName + " - " + project.Name + " - " + activity.Name
+	emptyDays(&row.Days)
+	for i, date := range s.days(from) {
+		row.Days[i].DayDate = api.NewDate(date)
+	}
+	return row
+}
+
+//emptyDays sets days, which is a pointer to one of the anonymous Days slices in the api package, to a week of empty days
+func emptyDays(days interface{}) {
+	err := json.Unmarshal([]byte(`[{},{},{},{},{},{},{}]`), days)
+	if err != nil {
+		panic(err)
+	}
+}
+
+//clone deep copies src into dst by way of json
+func clone(src interface{}, dst interface{}) {
+	data, err := json.Marshal(src)
+	if err != nil {
+		panic(err)
+	}
+	err = json.Unmarshal(data, dst)
+	if err != nil {
+		panic(err)
+	}
+}
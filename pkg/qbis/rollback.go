@@ -0,0 +1,127 @@
+package qbis
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/flipb/qbis-time/pkg/qbis/api"
+)
+
+//PartialSaveError is returned by Save when a section could not be saved after other sections were.
+//The sections that were saved are rolled back, unless WithoutRollback was used.
+type PartialSaveError struct {
+	Failed Section // the section that could not be saved
+	Err    error   // why it could not be saved
+
+	Committed    []Section // the sections that were saved before the failure
+	RolledBack   []Section // the committed sections that were restored to the values they had when the week was loaded
+	Inconsistent []Section // the committed sections that still hold the new values in Qbis
+
+	// RollbackErrors holds why rolling back failed, for each inconsistent section it was attempted for
+	RollbackErrors map[Section]error
+}
+
+func (e *PartialSaveError) Error() string {
+	parts := []string{e.Err.Error(), "committed: " + joinSections(e.Committed)}
+	if len(e.RolledBack) > 0 {
+		parts = append(parts, "rolled back: "+joinSections(e.RolledBack))
+	}
+	if len(e.Inconsistent) > 0 {
+		parts = append(parts, "left inconsistent: "+joinSections(e.Inconsistent))
+	}
+	for _, section := range e.Inconsistent {
+		if err, ok := e.RollbackErrors[section]; ok {
+			parts = append(parts, fmt.Sprintf("rolling back %v failed: %v", section, err))
+		}
+	}
+	return strings.Join(parts, "; ")
+}
+
+func (e *PartialSaveError) Unwrap() error {
+	return e.Err
+}
+
+func joinSections(sections []Section) string {
+	names := make([]string, 0, len(sections))
+	for _, s := range sections {
+		names = append(names, s.String())
+	}
+	return strings.Join(names, ", ")
+}
+
+//undo rolls back the committed sections after the failed section could not be saved
+func (w *Week) undo(ctx context.Context, failed Section, err error, committed []Section, result *SaveResult, o *saveOptions) error {
+	partial := &PartialSaveError{
+		Failed:         failed,
+		Err:            err,
+		Committed:      committed,
+		RolledBack:     make([]Section, 0),
+		Inconsistent:   make([]Section, 0),
+		RollbackErrors: make(map[Section]error),
+	}
+	if o.noRollback {
+		partial.Inconsistent = append(partial.Inconsistent, committed...)
+		return partial
+	}
+
+	restored, rollbackErr := w.restoredSheet()
+	if rollbackErr != nil {
+		for _, section := range committed {
+			partial.Inconsistent = append(partial.Inconsistent, section)
+			partial.RollbackErrors[section] = rollbackErr
+		}
+		return partial
+	}
+
+	// the save may have failed because the context is done, the rollback has to be sent anyway but not wait forever
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), o.rollbackTimeout)
+	defer cancel()
+
+	// undo in the reverse order, reporting in the order they were saved
+	for i := len(committed) - 1; i >= 0; i-- {
+		section := committed[i]
+		w.client.logger.Printf("rolling back %v after %v failed", section, failed)
+		rollbackErr = w.saveSection(ctx, section, restored, newSaveResult())
+		if rollbackErr != nil {
+			partial.Inconsistent = append([]Section{section}, partial.Inconsistent...)
+			partial.RollbackErrors[section] = rollbackErr
+			continue
+		}
+		partial.RolledBack = append([]Section{section}, partial.RolledBack...)
+		result.setRolledBack(section)
+		// the changes are no longer in Qbis, so they still have to be saved
+		w.markChanged(section)
+	}
+	return partial
+}
+
+//restoredSheet returns a copy of the sheet with every changed value set back to what it was when the week was loaded.
+//Activities added since then are kept, with their values cleared, so that rolling back removes them from Qbis.
+func (w *Week) restoredSheet() (*api.TimesheetData, error) {
+	if w.original == nil {
+		return nil, fmt.Errorf("the week has no original values to roll back to")
+	}
+	restored, err := cloneSheet(w.sheet)
+	if err != nil {
+		return nil, err
+	}
+
+	original := w.flatten(w.original)
+	edited := w.flatten(w.sheet)
+	changed := make(map[Cell]bool)
+	for _, set := range []cells{original, edited} {
+		for c := range set {
+			if original[c] != edited[c] {
+				changed[c] = true
+			}
+		}
+	}
+	for _, c := range sortedCells(changed) {
+		err = w.setCell(restored, c, original[c])
+		if err != nil {
+			return nil, fmt.Errorf("unable to restore %v: %v", c, err)
+		}
+	}
+	return restored, nil
+}
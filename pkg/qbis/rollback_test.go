@@ -0,0 +1,117 @@
+package qbis_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/flipb/qbis-time/pkg/qbis"
+	"github.com/flipb/qbis-time/pkg/qbis/api"
+	"github.com/flipb/qbis-time/pkg/qbis/qbistest"
+)
+
+//failingAPI fails to save working time, and lets rollback decide what happens when salary time is saved a second time
+type failingAPI struct {
+	*qbistest.MemoryAPI
+	cancel      context.CancelFunc
+	salarySaves int
+	rollback    func(ctx context.Context) error
+}
+
+func (f *failingAPI) SaveSalaryTimeContext(ctx context.Context, time api.EmployeeSalaryTime) (*api.SaveSalaryTimeResponse, error) {
+	f.salarySaves++
+	if f.salarySaves > 1 {
+		err := f.rollback(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return f.MemoryAPI.SaveSalaryTimeContext(ctx, time)
+}
+
+func (f *failingAPI) SaveWorkingTimeContext(ctx context.Context, time api.EmployeeWorkingTime) (*api.SaveWorkingTimeResponse, error) {
+	if f.cancel != nil {
+		f.cancel()
+		return nil, ctx.Err()
+	}
+	return nil, errors.New("working time is broken")
+}
+
+func TestRollback(t *testing.T) {
+	tests := []struct {
+		name       string
+		cancel     bool
+		rollback   func(ctx context.Context) error
+		rolledBack bool
+		err        error
+	}{
+		{
+			name:   "after the context was canceled",
+			cancel: true,
+			rollback: func(ctx context.Context) error {
+				return ctx.Err()
+			},
+			rolledBack: true,
+		},
+		{
+			name: "times out",
+			rollback: func(ctx context.Context) error {
+				<-ctx.Done()
+				return ctx.Err()
+			},
+			err: context.DeadlineExceeded,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			backend := &failingAPI{MemoryAPI: qbistest.NewMemoryAPI(qbistest.NewStore()), rollback: test.rollback}
+			if test.cancel {
+				backend.cancel = cancel
+			}
+			c, err := qbis.NewClientFromAPIClient(backend)
+			if err != nil {
+				t.Fatalf("NewClientFromAPIClient: %v", err)
+			}
+
+			w, err := c.Week(time.Date(2024, time.March, 6, 12, 0, 0, 0, time.Local))
+			if err != nil {
+				t.Fatalf("Week: %v", err)
+			}
+			d, err := w.Weekday(time.Wednesday)
+			if err != nil {
+				t.Fatalf("Weekday: %v", err)
+			}
+			if err := d.SetSalaryTime(qbistest.SickLeaveActivityID, -60); err != nil {
+				t.Fatalf("SetSalaryTime: %v", err)
+			}
+			if err := d.SetWorkingHours(8*60, 17*60); err != nil {
+				t.Fatalf("SetWorkingHours: %v", err)
+			}
+
+			started := time.Now()
+			_, err = w.SaveContext(ctx, qbis.WithRollbackTimeout(50*time.Millisecond))
+			if elapsed := time.Since(started); elapsed > 5*time.Second {
+				t.Errorf("Save took %v", elapsed)
+			}
+			var partial *qbis.PartialSaveError
+			if !errors.As(err, &partial) {
+				t.Fatalf("expected a *qbis.PartialSaveError, got %v", err)
+			}
+			if backend.salarySaves != 2 {
+				t.Fatalf("salary time was saved %d times, want 2", backend.salarySaves)
+			}
+			if test.rolledBack {
+				if len(partial.RolledBack) != 1 || partial.RolledBack[0] != qbis.SectionSalaryTime {
+					t.Errorf("rolled back %v, want salary time: %v", partial.RolledBack, err)
+				}
+				return
+			}
+			if len(partial.Inconsistent) != 1 || !errors.Is(partial.RollbackErrors[qbis.SectionSalaryTime], test.err) {
+				t.Errorf("inconsistent %v, want salary time to fail with %v: %v", partial.Inconsistent, test.err, err)
+			}
+		})
+	}
+}
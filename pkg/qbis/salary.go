@@ -1,5 +1,10 @@
 package qbis
 
+import (
+	"fmt"
+	"strings"
+)
+
 //SalaryActivity represents a Salary Activity (Sick leave, vacation etc.)
 type SalaryActivity struct {
 	week  *Week
@@ -13,28 +18,44 @@ type SalaryActivity struct {
 	//typeCode int	// type of activity. Defines how time is calculated
 }
 
+//DefaultSalaryActivityError is returned by Week.SalaryTimeActivities when the week has more than one default salary activity
+type DefaultSalaryActivityError struct {
+	ActivityIDs []int // the default activities
+}
+
+func (e *DefaultSalaryActivityError) Error() string {
+	ids := make([]string, 0, len(e.ActivityIDs))
+	for _, id := range e.ActivityIDs {
+		ids = append(ids, fmt.Sprint(id))
+	}
+	return fmt.Sprintf("more than one default salary activity: %s", strings.Join(ids, ", "))
+}
+
 //salaryTimeActivities returns a list of all available SalaryTime activities
-func salaryTimeActivities(week *Week) []SalaryActivity {
+func salaryTimeActivities(week *Week) ([]SalaryActivity, error) {
 	var stActivities = make([]SalaryActivity, 0)
 
 	// get default first (special case)
-	foundDefault := false
+	defaults := make([]int, 0)
 	for _, x := range week.sheet.ListOfSalaryTime {
-		if x.IsDefault && !foundDefault {
-			foundDefault = true
-			stActivities = append(stActivities, SalaryActivity{
-				week:      week,
-				key:       x.ActivityID,
-				value:     x.ActivityName,
-				isDefault: true,
-				//allowNegative: x.AllowNegative,
-				//allowPositive: x.AllowPositive,
-			})
+		if !x.IsDefault {
 			continue
 		}
-		if x.IsDefault {
-			panic("more than one default SalaryActivity possible??")
+		defaults = append(defaults, x.ActivityID)
+		if len(defaults) > 1 {
+			continue
 		}
+		stActivities = append(stActivities, SalaryActivity{
+			week:      week,
+			key:       x.ActivityID,
+			value:     x.ActivityName,
+			isDefault: true,
+			//allowNegative: x.AllowNegative,
+			//allowPositive: x.AllowPositive,
+		})
+	}
+	if len(defaults) > 1 {
+		return nil, &DefaultSalaryActivityError{ActivityIDs: defaults}
 	}
 
 	for _, y := range week.sheet.ListOfSalaryActivities {
@@ -44,7 +65,7 @@ func salaryTimeActivities(week *Week) []SalaryActivity {
 			value: y.Value,
 		})
 	}
-	return stActivities
+	return stActivities, nil
 }
 
 //ActivityID returns the ActivityID of the salary activity
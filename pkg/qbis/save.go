@@ -0,0 +1,208 @@
+package qbis
+
+import (
+	"time"
+
+	"github.com/flipb/qbis-time/pkg/qbis/api"
+)
+
+//Section is one of the parts of a week that Qbis saves separately
+type Section int
+
+const (
+	//SectionSalaryTime is the salary time activities, eg. sick leave and overtime
+	SectionSalaryTime Section = iota
+	//SectionWorkingTime is the arrival, departure and lunch of every day
+	SectionWorkingTime
+	//SectionProjectTime is the time spent on project activities
+	SectionProjectTime
+)
+
+//saveOrder is the order the sections are saved in
+var saveOrder = []Section{SectionSalaryTime, SectionWorkingTime, SectionProjectTime}
+
+func (s Section) String() string {
+	switch s {
+	case SectionSalaryTime:
+		return "salary time"
+	case SectionWorkingTime:
+		return "working time"
+	case SectionProjectTime:
+		return "project time"
+	default:
+		return "unknown section"
+	}
+}
+
+//DefaultRollbackTimeout is how long Save waits for Qbis to roll back the sections that were saved when a later section fails
+const DefaultRollbackTimeout = 30 * time.Second
+
+//SaveOption changes how Week.Save saves the week
+type SaveOption func(*saveOptions)
+
+type saveOptions struct {
+	force           bool
+	noRollback      bool
+	noValidation    bool
+	rollbackTimeout time.Duration
+}
+
+func newSaveOptions(opts []SaveOption) *saveOptions {
+	o := &saveOptions{rollbackTimeout: DefaultRollbackTimeout}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+//WithForce saves the week without checking if it was changed in Qbis since it was loaded,
+//overwriting any such changes in the sections that are saved
+func WithForce() SaveOption {
+	return func(o *saveOptions) {
+		o.force = true
+	}
+}
+
+//WithoutValidation saves the week without checking the changes with the rules of the client first
+func WithoutValidation() SaveOption {
+	return func(o *saveOptions) {
+		o.noValidation = true
+	}
+}
+
+//WithoutRollback keeps the sections that were saved when a later section fails,
+//instead of posting their original values back
+func WithoutRollback() SaveOption {
+	return func(o *saveOptions) {
+		o.noRollback = true
+	}
+}
+
+//WithRollbackTimeout sets how long Save waits for the rollback when a section fails (default DefaultRollbackTimeout).
+//The rollback is sent even if the context of the save is done, so this is what bounds it.
+func WithRollbackTimeout(timeout time.Duration) SaveOption {
+	return func(o *saveOptions) {
+		if timeout > 0 {
+			o.rollbackTimeout = timeout
+		}
+	}
+}
+
+//SectionResult is the outcome of saving one section of the week
+type SectionResult struct {
+	Section   Section
+	Attempted bool  // false if the section was not sent to Qbis, because it had not changed or an earlier section failed
+	Saved     bool  // true if Qbis saved the section
+	Err       error // why the section was not saved
+
+	RolledBack bool // true if the section was saved and then restored because a later section failed
+}
+
+//SaveMessage is a validation string returned by Qbis when saving, eg. the ResetWarning of the salary time
+type SaveMessage struct {
+	Field   string // name of the field in the response, eg. "ResetWarning"
+	Message string
+}
+
+func (m SaveMessage) String() string {
+	return m.Field + ": " + m.Message
+}
+
+//SaveResult holds everything Qbis said about a Week.Save: the outcome of every section, limit errors and warnings,
+//validation messages, the raw responses of the sections that were saved and the refreshed timesheet
+type SaveResult struct {
+	Sections []SectionResult // in the order they are saved
+
+	LimitErrors   []api.LimitResult
+	LimitWarnings []api.LimitResult
+	Messages      []SaveMessage
+
+	WorkingTime *api.SaveWorkingTimeResponse
+	SalaryTime  *api.SaveSalaryTimeResponse
+	ProjectTime *api.SaveProjectTimeResponse
+
+	// Timesheet is the week as Qbis returned it after saving, nil if it could not be refreshed
+	Timesheet *api.TimesheetData
+}
+
+func newSaveResult() *SaveResult {
+	r := &SaveResult{}
+	for _, section := range saveOrder {
+		r.Sections = append(r.Sections, SectionResult{Section: section})
+	}
+	return r
+}
+
+//Section returns the outcome of saving the section
+func (r *SaveResult) Section(section Section) SectionResult {
+	for _, s := range r.Sections {
+		if s.Section == section {
+			return s
+		}
+	}
+	return SectionResult{Section: section}
+}
+
+//Saved returns true if every section that was attempted was saved
+func (r *SaveResult) Saved() bool {
+	for _, s := range r.Sections {
+		if s.Attempted && !s.Saved {
+			return false
+		}
+	}
+	return true
+}
+
+//setSection records the outcome of saving the section
+func (r *SaveResult) setSection(section Section, err error) {
+	for i := range r.Sections {
+		if r.Sections[i].Section == section {
+			r.Sections[i].Attempted = true
+			r.Sections[i].Saved = err == nil
+			r.Sections[i].Err = err
+		}
+	}
+}
+
+//setRolledBack records that the saved section was restored to its original values
+func (r *SaveResult) setRolledBack(section Section) {
+	for i := range r.Sections {
+		if r.Sections[i].Section == section {
+			r.Sections[i].Saved = false
+			r.Sections[i].RolledBack = true
+		}
+	}
+}
+
+//HasWarnings returns true if Qbis returned any limit results or validation messages
+func (r *SaveResult) HasWarnings() bool {
+	return len(r.LimitErrors) > 0 || len(r.LimitWarnings) > 0 || len(r.Messages) > 0
+}
+
+func (r *SaveResult) addMessage(field string, message string) {
+	if message != "" {
+		r.Messages = append(r.Messages, SaveMessage{Field: field, Message: message})
+	}
+}
+
+func (r *SaveResult) addWorkingTime(response *api.SaveWorkingTimeResponse) {
+	r.WorkingTime = response
+	r.addMessage("Saved", response.Saved)
+}
+
+func (r *SaveResult) addSalaryTime(response *api.SaveSalaryTimeResponse) {
+	r.SalaryTime = response
+	r.LimitErrors = append(r.LimitErrors, response.LimitErrorResults...)
+	r.LimitWarnings = append(r.LimitWarnings, response.LimitWarningResults...)
+	r.addMessage("FromToValidationResult", response.FromToValidationResult)
+	r.addMessage("InvalidFullDaysError", response.InvalidFullDaysError)
+	r.addMessage("InvalidPartialDaysError", response.InvalidPartialDaysError)
+	r.addMessage("ResetWarning", response.ResetWarning)
+}
+
+func (r *SaveResult) addProjectTime(response *api.SaveProjectTimeResponse) {
+	r.ProjectTime = response
+	r.LimitErrors = append(r.LimitErrors, response.LimitErrorResults...)
+	r.LimitWarnings = append(r.LimitWarnings, response.LimitWarningResults...)
+	r.addMessage("DuplicatedActivitiesError", response.DuplicatedActivitiesError)
+}
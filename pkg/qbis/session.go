@@ -0,0 +1,75 @@
+package qbis
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/flipb/qbis-time/pkg/qbis/api"
+)
+
+//NewClientFromSession creates a new qbis client from a stored session, without credentials.
+//Returns an error if there is no stored session or if it has expired.
+//The url and session store options are ignored, the url is taken from the stored session.
+func NewClientFromSession(store api.SessionStore, opts ...Option) (*Client, error) {
+	return NewClientFromSessionContext(context.Background(), store, opts...)
+}
+
+//NewClientFromSessionContext is like NewClientFromSession but with a context
+func NewClientFromSessionContext(ctx context.Context, store api.SessionStore, opts ...Option) (*Client, error) {
+	o := newOptions(opts)
+	o.store = store
+	return newClientFromSession(ctx, o, nil)
+}
+
+//login holds the credentials given to NewClient
+type login struct {
+	company, user, password string
+}
+
+//newClientFromSession creates a client from the session in the options session store.
+//If creds is not nil the session has to be for the url of the options and the company and user of creds,
+//and the client logs in again with them when the session expires.
+func newClientFromSession(ctx context.Context, o *options, creds *login) (*Client, error) {
+	session, err := o.store.LoadSession()
+	if err != nil {
+		return nil, err
+	}
+	qbisClient := o.apiClient(session.URL)
+	if creds != nil {
+		// never use a session of another user, or one for another qbis than the one asked for
+		if !sameURL(session.URL, o.url) || session.Company != creds.company || session.User != creds.user {
+			return nil, fmt.Errorf("stored session is for %s at %s", session.User, session.URL)
+		}
+		err = qbisClient.RestoreSessionWithCredentials(session, creds.company, creds.user, creds.password)
+	} else {
+		err = qbisClient.RestoreSession(session)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := newClient(ctx, qbisClient, o)
+	if err != nil {
+		return nil, fmt.Errorf("stored session is not valid: %w", err)
+	}
+	return client, nil
+}
+
+//sameURL returns true if the urls only differ by a trailing slash
+func sameURL(a string, b string) bool {
+	return strings.TrimSuffix(a, "/") == strings.TrimSuffix(b, "/")
+}
+
+//saveSession saves the session of the api client in the store
+func saveSession(qbisClient *api.Client, store api.SessionStore) error {
+	session, err := qbisClient.Session()
+	if err != nil {
+		return err
+	}
+	err = store.SaveSession(session)
+	if err != nil {
+		return fmt.Errorf("unable to save session: %w", err)
+	}
+	return nil
+}
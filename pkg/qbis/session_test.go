@@ -0,0 +1,78 @@
+package qbis_test
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/flipb/qbis-time/pkg/qbis"
+	"github.com/flipb/qbis-time/pkg/qbis/api"
+	"github.com/flipb/qbis-time/pkg/qbis/qbistest"
+)
+
+func TestRestoredSessionLogsInAgain(t *testing.T) {
+	srv := qbistest.NewServer()
+	defer srv.Close()
+	store := api.NewFileSessionStore(filepath.Join(t.TempDir(), "session.json"))
+
+	newClient := func() *qbis.Client {
+		c, err := qbis.NewClient(qbistest.DefaultCompany, qbistest.DefaultUser, qbistest.DefaultPassword,
+			qbis.WithBaseURL(srv.URL), qbis.WithSessionStore(store))
+		if err != nil {
+			t.Fatalf("NewClient: %v", err)
+		}
+		return c
+	}
+	newClient()
+
+	// the second client restores the stored session
+	c := newClient()
+	srv.ExpireSessions()
+
+	_, err := c.WeekNow()
+	if err != nil {
+		t.Fatalf("restored client did not log in again: %v", err)
+	}
+
+	// the new session was saved, so a client without credentials can use it
+	_, err = qbis.NewClientFromSession(store, qbis.WithBaseURL(srv.URL))
+	if err != nil {
+		t.Fatalf("session was not saved after logging in again: %v", err)
+	}
+}
+
+func TestStoredSessionOfAnotherServerOrUser(t *testing.T) {
+	staging := qbistest.NewServer()
+	defer staging.Close()
+	production := qbistest.NewServer()
+	defer production.Close()
+	store := api.NewFileSessionStore(filepath.Join(t.TempDir(), "session.json"))
+
+	_, err := qbis.NewClient(qbistest.DefaultCompany, qbistest.DefaultUser, qbistest.DefaultPassword,
+		qbis.WithBaseURL(staging.URL), qbis.WithSessionStore(store))
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+
+	// another user does not get the stored session
+	_, err = qbis.NewClient(qbistest.DefaultCompany, "someone else", qbistest.DefaultPassword,
+		qbis.WithBaseURL(staging.URL), qbis.WithSessionStore(store))
+	if !errors.Is(err, api.ErrInvalidCredentials) {
+		t.Fatalf("expected the login of another user to fail, got %v", err)
+	}
+
+	// the same user at another url logs in there
+	_, err = qbis.NewClient(qbistest.DefaultCompany, qbistest.DefaultUser, qbistest.DefaultPassword,
+		qbis.WithBaseURL(production.URL), qbis.WithSessionStore(store))
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	session, err := store.LoadSession()
+	if err != nil {
+		t.Fatalf("LoadSession: %v", err)
+	}
+	if session.URL != production.URL+"/" || session.Company != qbistest.DefaultCompany || session.User != qbistest.DefaultUser {
+		t.Errorf("stored session is for %s %s at %s, want %s %s at %s",
+			session.Company, session.User, session.URL, qbistest.DefaultCompany, qbistest.DefaultUser, production.URL)
+	}
+}
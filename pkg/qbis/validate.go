@@ -0,0 +1,358 @@
+package qbis
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/flipb/qbis-time/pkg/qbis/api"
+)
+
+//minutesPerDay is the largest time of day Qbis accepts, a departure at midnight at the end of the day
+const minutesPerDay = 24 * 60
+
+//ValidationError is a change to the week that Qbis would not accept, found before saving
+type ValidationError struct {
+	Rule    string    // name of the rule that was broken
+	Cell    Cell      // the day, activity and field of the change
+	Day     time.Time // midnight of the day, in the time zone of the client
+	Message string
+}
+
+func (e ValidationError) Error() string {
+	return fmt.Sprintf("%v: %s (%s)", e.Cell, e.Message, e.Rule)
+}
+
+//ValidationErrors is every change to a week that broke a rule
+type ValidationErrors []ValidationError
+
+func (e ValidationErrors) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, v := range e {
+		messages = append(messages, v.Error())
+	}
+	return fmt.Sprintf("week is not valid: %s", strings.Join(messages, "; "))
+}
+
+//Rule is a policy the changes to a week have to follow to be saved.
+//Add your own with the WithRules option.
+type Rule interface {
+	// Name identifies the rule in validation errors, eg. "lunch-limits"
+	Name() string
+	// Check returns the changes that break the rule. The changes are those returned by Week.Diff.
+	Check(w *Week, changes []Change) []ValidationError
+}
+
+//NewRule creates a Rule that checks every change by itself.
+//check returns why the change breaks the rule, or an empty string if it does not.
+func NewRule(name string, check func(w *Week, c Change) string) Rule {
+	return changeRule{name: name, check: check}
+}
+
+type changeRule struct {
+	name  string
+	check func(w *Week, c Change) string
+}
+
+func (r changeRule) Name() string {
+	return r.name
+}
+
+func (r changeRule) Check(w *Week, changes []Change) []ValidationError {
+	errs := make([]ValidationError, 0)
+	for _, c := range changes {
+		if message := r.check(w, c); message != "" {
+			errs = append(errs, ValidationError{Rule: r.name, Cell: c.Cell, Day: c.Day, Message: message})
+		}
+	}
+	return errs
+}
+
+//Validator checks the changes to a week with a set of rules
+type Validator struct {
+	rules []Rule
+}
+
+//NewValidator creates a Validator with the rules, use DefaultRules to include the built in rules
+func NewValidator(rules ...Rule) *Validator {
+	return &Validator{rules: rules}
+}
+
+//Rules returns the rules of the validator, in the order they are checked
+func (v *Validator) Rules() []Rule {
+	if v == nil {
+		return nil
+	}
+	return append([]Rule(nil), v.rules...)
+}
+
+//Validate returns every change that breaks a rule
+func (v *Validator) Validate(w *Week, changes []Change) ValidationErrors {
+	errs := make(ValidationErrors, 0)
+	if v == nil {
+		return errs
+	}
+	for _, rule := range v.rules {
+		errs = append(errs, rule.Check(w, changes)...)
+	}
+	return errs
+}
+
+//Validate checks the changes to the week with the rules of the client, like Save does before saving
+func (w *Week) Validate() ValidationErrors {
+	return w.client.validator.Validate(w, w.Diff())
+}
+
+//DefaultRules returns the rules built from what Qbis tells about the week. They are used unless
+//the WithoutDefaultRules option is given:
+//
+//	read-only          the section is read only that day
+//	disabled           the section is disabled that day
+//	month-closed       the month of the day is closed
+//	employment-period  the day is outside the employment period
+//	working-time       arrival and departure are times of day, in order, with room for the lunch
+//	lunch-limits       the lunch is within the LunchMinimum and LunchMaximum of the day
+//	salary-sign        the salary activity allows negative or positive minutes
+//	salary-limits      the salary minutes are within the LowerLimit and UpperLimit of the activity
+//	project-locked     the project time is not invoiced, read only or outside the dates of the activity
+//	project-minutes    the project minutes are not negative
+func DefaultRules() []Rule {
+	return []Rule{
+		readOnlyRule,
+		disabledRule,
+		monthClosedRule,
+		employmentPeriodRule,
+		workingTimeRule{},
+		lunchLimitsRule{},
+		salarySignRule,
+		salaryLimitsRule,
+		projectLockedRule,
+		projectMinutesRule,
+	}
+}
+
+var readOnlyRule = NewRule("read-only", func(w *Week, c Change) string {
+	ds := w.daySettingOf(c.Date)
+	if ds == nil {
+		return ""
+	}
+	if (c.Section == SectionSalaryTime && ds.IsReadOnlySalaryTime) ||
+		(c.Section == SectionWorkingTime && ds.IsReadOnlyWorkingTime) ||
+		(c.Section == SectionProjectTime && ds.IsReadOnlyProjectTime) {
+		return fmt.Sprintf("%v is read only", c.Section)
+	}
+	return ""
+})
+
+var disabledRule = NewRule("disabled", func(w *Week, c Change) string {
+	ds := w.daySettingOf(c.Date)
+	if ds == nil {
+		return ""
+	}
+	disabled, tooltip := false, ""
+	switch c.Section {
+	case SectionSalaryTime:
+		disabled, tooltip = ds.IsDisabledSalaryTime, ds.DisabledTooltipSalaryTime
+	case SectionWorkingTime:
+		disabled, tooltip = ds.IsDisabledWorkingTime, ds.DisabledTooltipWorkingTime
+	case SectionProjectTime:
+		disabled, tooltip = ds.IsDisabledProjectTime, ds.DisabledTooltipProjectTime
+	}
+	if !disabled {
+		return ""
+	}
+	if tooltip != "" {
+		return fmt.Sprintf("%v is disabled: %s", c.Section, tooltip)
+	}
+	return fmt.Sprintf("%v is disabled", c.Section)
+})
+
+var monthClosedRule = NewRule("month-closed", func(w *Week, c Change) string {
+	ds := w.daySettingOf(c.Date)
+	if ds == nil {
+		return ""
+	}
+	closed := ds.IsMonthClosedWorkingTime
+	if c.Section == SectionProjectTime {
+		closed = ds.IsMonthClosedProjectTime
+	}
+	// there is no flag for the salary time, it is closed together with the working time
+	if closed {
+		return fmt.Sprintf("%v is closed for %s", c.Section, ds.MonthName)
+	}
+	return ""
+})
+
+var employmentPeriodRule = NewRule("employment-period", func(w *Week, c Change) string {
+	ds := w.daySettingOf(c.Date)
+	// clearing values is allowed
+	if ds == nil || !ds.IsOutsideEmploymentPeriod || c.New == "" {
+		return ""
+	}
+	return "day is outside the employment period"
+})
+
+var salarySignRule = NewRule("salary-sign", func(w *Week, c Change) string {
+	row := salaryRow(w.sheet, c.Activity)
+	if c.Section != SectionSalaryTime || c.Field != "DayMinutes" || row == nil {
+		return ""
+	}
+	minutes := minutesOf(c.New)
+	if minutes < 0 && !row.AllowNegative {
+		return fmt.Sprintf("%s does not allow negative minutes: %d", row.ActivityName, minutes)
+	}
+	if minutes > 0 && !row.AllowPositive {
+		return fmt.Sprintf("%s does not allow positive minutes: %d", row.ActivityName, minutes)
+	}
+	return ""
+})
+
+var salaryLimitsRule = NewRule("salary-limits", func(w *Week, c Change) string {
+	row := salaryRow(w.sheet, c.Activity)
+	if c.Section != SectionSalaryTime || c.Field != "DayMinutes" || row == nil {
+		return ""
+	}
+	// limits that are 0 are not set
+	minutes := minutesOf(c.New)
+	if row.UpperLimit != 0 && minutes > row.UpperLimit {
+		return fmt.Sprintf("%s is limited to %d minutes a day: %d", row.ActivityName, row.UpperLimit, minutes)
+	}
+	if row.LowerLimit != 0 && minutes < row.LowerLimit {
+		return fmt.Sprintf("%s is limited to at least %d minutes a day: %d", row.ActivityName, row.LowerLimit, minutes)
+	}
+	return ""
+})
+
+var projectLockedRule = NewRule("project-locked", func(w *Week, c Change) string {
+	row := projectRow(w.sheet, c.Activity)
+	day := w.dayIndex(c.Date)
+	if c.Section != SectionProjectTime || row == nil || day < 0 || day >= len(row.Days) {
+		return ""
+	}
+	switch {
+	case row.Days[day].IsInvoiced:
+		return fmt.Sprintf("%s is already invoiced", row.ActivityName)
+	case row.Days[day].IsReadOnly:
+		return fmt.Sprintf("%s is read only", row.ActivityName)
+	case row.Days[day].IsOutsideActivityDateSpan && c.New != "":
+		return fmt.Sprintf("%s is only open %s", row.ActivityName, row.ActivityDateSpanString)
+	}
+	return ""
+})
+
+var projectMinutesRule = NewRule("project-minutes", func(w *Week, c Change) string {
+	if c.Section != SectionProjectTime || c.Field != "DayMinutes" || minutesOf(c.New) >= 0 {
+		return ""
+	}
+	return fmt.Sprintf("%s has negative minutes: %s", c.ActivityName, c.New)
+})
+
+//workingTimeRule checks the arrival, departure and lunch of every day with changed working time
+type workingTimeRule struct{}
+
+func (workingTimeRule) Name() string {
+	return "working-time"
+}
+
+func (r workingTimeRule) Check(w *Week, changes []Change) []ValidationError {
+	errs := make([]ValidationError, 0)
+	for _, c := range changedDays(changes, SectionWorkingTime) {
+		wt := w.workingTimeOf(c.Date)
+		if wt == nil {
+			continue
+		}
+		add := func(field string, format string, v ...interface{}) {
+			cell := Cell{Section: SectionWorkingTime, Date: c.Date, Field: field}
+			errs = append(errs, ValidationError{Rule: r.Name(), Cell: cell, Day: c.Day, Message: fmt.Sprintf(format, v...)})
+		}
+
+		if wt.Arrive < 0 || wt.Arrive >= minutesPerDay {
+			add("Arrive", "arrival is not a time of day: %d minutes", wt.Arrive)
+		}
+		if wt.Leave < 0 || wt.Leave > minutesPerDay {
+			add("Leave", "departure is not a time of day: %d minutes", wt.Leave)
+		}
+		if wt.Lunch < 0 {
+			add("Lunch", "lunch is negative: %d minutes", wt.Lunch)
+		}
+		if wt.Arrive == 0 || wt.Leave == 0 {
+			continue
+		}
+		if wt.Leave < wt.Arrive {
+			add("Leave", "departure %s is before arrival %s", formatValue("Leave", strconv.Itoa(int(wt.Leave))), formatValue("Arrive", strconv.Itoa(int(wt.Arrive))))
+		} else if wt.Lunch > wt.Leave-wt.Arrive {
+			add("Lunch", "lunch of %d minutes is longer than the %d minutes between arrival and departure", wt.Lunch, wt.Leave-wt.Arrive)
+		}
+	}
+	return errs
+}
+
+//lunchLimitsRule checks the lunch of every day with changed working time against the limits of the day
+type lunchLimitsRule struct{}
+
+func (lunchLimitsRule) Name() string {
+	return "lunch-limits"
+}
+
+func (r lunchLimitsRule) Check(w *Week, changes []Change) []ValidationError {
+	errs := make([]ValidationError, 0)
+	for _, c := range changedDays(changes, SectionWorkingTime) {
+		wt := w.workingTimeOf(c.Date)
+		ds := w.daySettingOf(c.Date)
+		// days without both arrival and departure were not worked
+		if wt == nil || ds == nil || wt.Arrive == 0 || wt.Leave == 0 {
+			continue
+		}
+		message := ""
+		switch {
+		case ds.LunchMinimum != 0 && int(wt.Lunch) < ds.LunchMinimum:
+			message = fmt.Sprintf("lunch has to be at least %d minutes: %d", ds.LunchMinimum, wt.Lunch)
+		case ds.LunchMaximum != 0 && int(wt.Lunch) > ds.LunchMaximum:
+			message = fmt.Sprintf("lunch can be at most %d minutes: %d", ds.LunchMaximum, wt.Lunch)
+		default:
+			continue
+		}
+		cell := Cell{Section: SectionWorkingTime, Date: c.Date, Field: "Lunch"}
+		errs = append(errs, ValidationError{Rule: r.Name(), Cell: cell, Day: c.Day, Message: message})
+	}
+	return errs
+}
+
+//changedDays returns the first change of every day with changes in the section
+func changedDays(changes []Change, section Section) []Change {
+	days := make([]Change, 0)
+	seen := make(map[string]bool)
+	for _, c := range changes {
+		if c.Section != section || seen[c.Date] {
+			continue
+		}
+		seen[c.Date] = true
+		days = append(days, c)
+	}
+	return days
+}
+
+//daySettingOf returns the settings of the day formatted by dayKey, or nil if it is not in the sheet
+func (w *Week) daySettingOf(date string) *api.DaySetting {
+	day := w.dayIndex(date)
+	if day < 0 || day >= len(w.sheet.DaySettings) {
+		return nil
+	}
+	return &w.sheet.DaySettings[day]
+}
+
+//workingTimeOf returns the working time of the day formatted by dayKey, or nil if it is not in the sheet
+func (w *Week) workingTimeOf(date string) *api.WorkingTime {
+	day := w.dayIndex(date)
+	if day < 0 || day >= len(w.sheet.WorkingTimeDays) {
+		return nil
+	}
+	return &w.sheet.WorkingTimeDays[day]
+}
+
+//minutesOf parses the value of a minutes cell, empty values are 0
+func minutesOf(value string) int {
+	minutes, _ := strconv.Atoi(value)
+	return minutes
+}
@@ -0,0 +1,276 @@
+package qbis_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/flipb/qbis-time/pkg/qbis"
+	"github.com/flipb/qbis-time/pkg/qbis/api"
+	"github.com/flipb/qbis-time/pkg/qbis/qbistest"
+)
+
+//updateWednesday returns a setup that changes the settings of wednesday in the stored week
+func updateWednesday(update func(ds *api.DaySetting)) func(t *testing.T, store *qbistest.Store) {
+	return func(t *testing.T, store *qbistest.Store) {
+		err := store.UpdateTimesheet(monday, func(sheet *api.TimesheetData) {
+			update(&sheet.DaySettings[2])
+		})
+		if err != nil {
+			t.Fatalf("UpdateTimesheet: %v", err)
+		}
+	}
+}
+
+func TestDefaultRules(t *testing.T) {
+	const wednesday = "2024-03-06"
+	arrival := qbis.Cell{Section: qbis.SectionWorkingTime, Date: wednesday, Field: "Arrive"}
+	lunch := qbis.Cell{Section: qbis.SectionWorkingTime, Date: wednesday, Field: "Lunch"}
+	compTime := qbis.Cell{Section: qbis.SectionSalaryTime, Activity: qbistest.CompTimeActivityID, Date: wednesday, Field: "DayMinutes"}
+	development := qbis.Cell{Section: qbis.SectionProjectTime, Activity: qbistest.DevelopmentActivityID, Date: wednesday, Field: "DayMinutes"}
+
+	tests := []struct {
+		rule    string
+		setup   func(t *testing.T, store *qbistest.Store) // before the week is loaded
+		change  func(d *qbis.Day) error
+		inQbis  func(sheet *api.TimesheetData) // after the change, picked up with Merge
+		cell    qbis.Cell
+		message string
+	}{
+		{
+			rule:    "read-only",
+			setup:   updateWednesday(func(ds *api.DaySetting) { ds.IsReadOnlyWorkingTime = true }),
+			change:  func(d *qbis.Day) error { return d.SetArrivalClock(8 * 60) },
+			cell:    arrival,
+			message: "working time is read only",
+		},
+		{
+			rule: "disabled",
+			setup: updateWednesday(func(ds *api.DaySetting) {
+				ds.IsDisabledProjectTime = true
+				ds.DisabledTooltipProjectTime = "locked by your manager"
+			}),
+			change:  func(d *qbis.Day) error { return d.SetProjectTime(qbistest.DevelopmentActivityID, 60) },
+			cell:    development,
+			message: "project time is disabled: locked by your manager",
+		},
+		{
+			rule:    "month-closed",
+			setup:   updateWednesday(func(ds *api.DaySetting) { ds.IsMonthClosedWorkingTime = true }),
+			change:  func(d *qbis.Day) error { return d.SetSalaryTime(qbistest.CompTimeActivityID, 60) },
+			cell:    compTime,
+			message: "salary time is closed for March",
+		},
+		{
+			rule:    "employment-period",
+			setup:   updateWednesday(func(ds *api.DaySetting) { ds.IsOutsideEmploymentPeriod = true }),
+			change:  func(d *qbis.Day) error { return d.SetArrivalClock(8 * 60) },
+			cell:    arrival,
+			message: "day is outside the employment period",
+		},
+		{
+			rule: "working-time",
+			change: func(d *qbis.Day) error {
+				d.SetBreakMinutes(90)
+				return d.SetWorkingHours(8*60, 9*60)
+			},
+			cell:    lunch,
+			message: "lunch of 90 minutes is longer than the 60 minutes between arrival and departure",
+		},
+		{
+			rule:    "lunch-limits",
+			setup:   updateWednesday(func(ds *api.DaySetting) { ds.LunchMinimum = 30 }),
+			change:  func(d *qbis.Day) error { return d.SetWorkingHours(8*60, 17*60) },
+			cell:    lunch,
+			message: "lunch has to be at least 30 minutes: 0",
+		},
+		{
+			// SetSalaryTime refuses minutes of the wrong sign, so the activity is changed in qbis after they were set
+			rule:   "salary-sign",
+			change: func(d *qbis.Day) error { return d.SetSalaryTime(qbistest.CompTimeActivityID, 60) },
+			inQbis: func(sheet *api.TimesheetData) {
+				sheet.ListOfSalaryTime[0].AllowPositive = false
+			},
+			cell:    compTime,
+			message: "Comp time does not allow positive minutes: 60",
+		},
+		{
+			rule: "salary-limits",
+			setup: func(t *testing.T, store *qbistest.Store) {
+				store.SalaryActivities[0].UpperLimit = 120
+			},
+			change:  func(d *qbis.Day) error { return d.SetSalaryTime(qbistest.CompTimeActivityID, 180) },
+			cell:    compTime,
+			message: "Comp time is limited to 120 minutes a day: 180",
+		},
+		{
+			rule: "project-locked",
+			setup: func(t *testing.T, store *qbistest.Store) {
+				row, err := store.ProjectActivity("1234", qbistest.DevelopmentActivityID, monday)
+				if err != nil {
+					t.Fatalf("ProjectActivity: %v", err)
+				}
+				row.Days[2].DayMinutes = 60
+				row.Days[2].IsInvoiced = true
+				err = store.UpdateTimesheet(monday, func(sheet *api.TimesheetData) {
+					sheet.ListOfProjectTime = append(sheet.ListOfProjectTime, *row)
+				})
+				if err != nil {
+					t.Fatalf("UpdateTimesheet: %v", err)
+				}
+			},
+			change:  func(d *qbis.Day) error { return d.SetProjectTime(qbistest.DevelopmentActivityID, 90) },
+			cell:    development,
+			message: "Development is already invoiced",
+		},
+		{
+			rule:    "project-minutes",
+			change:  func(d *qbis.Day) error { return d.SetProjectTime(qbistest.DevelopmentActivityID, -30) },
+			cell:    development,
+			message: "Development has negative minutes: -30",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.rule, func(t *testing.T) {
+			store := qbistest.NewStore()
+			if test.setup != nil {
+				test.setup(t, store)
+			}
+			w := newWeek(t, store)
+			d, err := w.Weekday(time.Wednesday)
+			if err != nil {
+				t.Fatalf("Weekday: %v", err)
+			}
+			if err := test.change(d); err != nil {
+				t.Fatalf("change: %v", err)
+			}
+			if test.inQbis != nil {
+				if err := store.UpdateTimesheet(monday, test.inQbis); err != nil {
+					t.Fatalf("UpdateTimesheet: %v", err)
+				}
+				if err := w.Merge(); err != nil {
+					t.Fatalf("Merge: %v", err)
+				}
+			}
+
+			want := qbis.ValidationErrors{{Rule: test.rule, Cell: test.cell, Day: monday.AddDate(0, 0, 2), Message: test.message}}
+			errs := w.Validate()
+			if !reflect.DeepEqual(errs, want) {
+				t.Fatalf("Validate:\n%v\nwant:\n%v", errs, want)
+			}
+
+			// Save checks the same rules, and sends nothing if one is broken
+			_, err = w.Save()
+			var validation qbis.ValidationErrors
+			if !errors.As(err, &validation) || !reflect.DeepEqual(validation, want) {
+				t.Errorf("Save returned %v, want the validation errors", err)
+			}
+			if got := len(qbis.NewValidator().Validate(w, w.Diff())); got != 0 {
+				t.Errorf("a validator without rules found %d errors", got)
+			}
+		})
+	}
+}
+
+func TestCustomRules(t *testing.T) {
+	noWeekends := qbis.NewRule("no-weekends", func(w *qbis.Week, c qbis.Change) string {
+		if weekday := c.Day.Weekday(); weekday == time.Saturday || weekday == time.Sunday {
+			return "no work on " + weekday.String()
+		}
+		return ""
+	})
+	saturday := qbis.Cell{Section: qbis.SectionWorkingTime, Date: "2024-03-09", Field: "Arrive"}
+	negative := qbis.Cell{Section: qbis.SectionProjectTime, Activity: qbistest.DevelopmentActivityID, Date: "2024-03-06", Field: "DayMinutes"}
+
+	tests := []struct {
+		name  string
+		opts  []qbis.Option
+		rules []string // the rules that are broken, in order
+		cells []qbis.Cell
+	}{
+		{"default rules", nil, []string{"project-minutes"}, []qbis.Cell{negative}},
+		{"with rules", []qbis.Option{qbis.WithRules(noWeekends)}, []string{"project-minutes", "no-weekends"}, []qbis.Cell{negative, saturday}},
+		{"without default rules", []qbis.Option{qbis.WithoutDefaultRules()}, []string{}, []qbis.Cell{}},
+		{
+			"only custom rules",
+			[]qbis.Option{qbis.WithoutDefaultRules(), qbis.WithRules(noWeekends)},
+			[]string{"no-weekends"},
+			[]qbis.Cell{saturday},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store := qbistest.NewStore()
+			w := newWeek(t, store, test.opts...)
+			d, err := w.Weekday(time.Wednesday)
+			if err != nil {
+				t.Fatalf("Weekday: %v", err)
+			}
+			if err := d.SetProjectTime(qbistest.DevelopmentActivityID, -30); err != nil {
+				t.Fatalf("SetProjectTime: %v", err)
+			}
+			d, err = w.Weekday(time.Saturday)
+			if err != nil {
+				t.Fatalf("Weekday: %v", err)
+			}
+			if err := d.SetArrivalClock(10 * 60); err != nil {
+				t.Fatalf("SetArrivalClock: %v", err)
+			}
+
+			rules, cells := []string{}, []qbis.Cell{}
+			for _, e := range w.Validate() {
+				rules = append(rules, e.Rule)
+				cells = append(cells, e.Cell)
+			}
+			if !reflect.DeepEqual(rules, test.rules) || !reflect.DeepEqual(cells, test.cells) {
+				t.Errorf("broken rules %v in %v, want %v in %v", rules, cells, test.rules, test.cells)
+			}
+
+			_, err = w.Save()
+			if (len(test.rules) == 0) != (err == nil) {
+				t.Errorf("Save: %v", err)
+			}
+		})
+	}
+}
+
+func TestSaveWithoutValidation(t *testing.T) {
+	store := qbistest.NewStore()
+	w := newWeek(t, store)
+	d, err := w.Weekday(time.Wednesday)
+	if err != nil {
+		t.Fatalf("Weekday: %v", err)
+	}
+	if err := d.SetProjectTime(qbistest.DevelopmentActivityID, -30); err != nil {
+		t.Fatalf("SetProjectTime: %v", err)
+	}
+	if len(w.Validate()) != 1 {
+		t.Fatalf("expected negative project time to break a rule")
+	}
+
+	if _, err := w.Save(qbis.WithoutValidation()); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	sheet, err := store.Timesheet("1234", monday, monday.AddDate(0, 0, 6))
+	if err != nil {
+		t.Fatalf("Timesheet: %v", err)
+	}
+	if len(sheet.ListOfProjectTime) != 1 || sheet.ListOfProjectTime[0].Days[2].DayMinutes != -30 {
+		t.Errorf("project time was not saved: %+v", sheet.ListOfProjectTime)
+	}
+}
+
+func TestDefaultRuleNames(t *testing.T) {
+	want := []string{
+		"read-only", "disabled", "month-closed", "employment-period", "working-time",
+		"lunch-limits", "salary-sign", "salary-limits", "project-locked", "project-minutes",
+	}
+	names := make([]string, 0)
+	for _, rule := range qbis.NewValidator(qbis.DefaultRules()...).Rules() {
+		names = append(names, rule.Name())
+	}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("default rules are %v, want %v", names, want)
+	}
+}
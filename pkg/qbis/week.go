@@ -1,6 +1,7 @@
 package qbis
 
 import (
+	"context"
 	"fmt"
 	"time"
 
@@ -15,76 +16,61 @@ type Week struct {
 
 	client Client
 
-	sheet   *api.TimesheetData
-	changed bool
+	sheet *api.TimesheetData
+	// original is a copy of the sheet as it was loaded, to find out if it was changed in Qbis before saving
+	original *api.TimesheetData
+	// changed holds the sections that have been modified since they were last saved
+	changed map[Section]bool
 }
 
-func (w *Week) projectTimeDays() []api.ProjectTime {
-	days := make([]api.ProjectTime, 0)
-
-	for i := range w.sheet.ListOfProjectTime {
-		activity := w.sheet.ListOfProjectTime[i]
-		for day := range activity.Days {
+//markChanged records that the section has to be saved
+func (w *Week) markChanged(section Section) {
+	if w.changed == nil {
+		w.changed = make(map[Section]bool)
+	}
+	w.changed[section] = true
+}
 
-			dayDate, err := api.DateStringToTime(activity.Days[day].DayDate)
-			if err != nil {
-				// TODO improve
-				panic(err)
-			}
-			activity.Days[day].DayDate = api.TimeToISODateString(dayDate)
+//ChangedSections returns the sections that have been modified and will be sent by Save
+func (w *Week) ChangedSections() []Section {
+	sections := make([]Section, 0)
+	for _, section := range saveOrder {
+		if w.changed[section] {
+			sections = append(sections, section)
 		}
+	}
+	return sections
+}
+
+func (w *Week) projectTimeDays(sheet *api.TimesheetData) []api.ProjectTime {
+	days := make([]api.ProjectTime, 0)
+
+	for _, activity := range sheet.ListOfProjectTime {
 		days = append(days, activity)
 	}
 	return days
 }
 
-func (w *Week) salaryTimeDays() []api.SalaryTimeBase {
+func (w *Week) salaryTimeDays(sheet *api.TimesheetData) []api.SalaryTimeBase {
 	days := make([]api.SalaryTimeBase, 0)
 
-	for i := range w.sheet.ListOfSalaryTime {
-		activity := w.sheet.ListOfSalaryTime[i].SalaryTimeBase
-		for day := range activity.Days {
-
-			dayDate, err := api.DateStringToTime(activity.Days[day].DayDate)
-			if err != nil {
-				// TODO improve
-				panic(err)
-			}
-			activity.Days[day].DayDate = api.TimeToISODateString(dayDate)
-		}
-		days = append(days, activity)
+	for _, activity := range sheet.ListOfSalaryTime {
+		days = append(days, activity.SalaryTimeBase)
 	}
 	return days
 }
 
-func (w *Week) workingTimeDays() []api.WorkingTimeBase {
+func (w *Week) workingTimeDays(sheet *api.TimesheetData) []api.WorkingTimeBase {
 	var days = make([]api.WorkingTimeBase, 0)
 
-	for i := range w.sheet.WorkingTimeDays {
-		x := w.sheet.WorkingTimeDays[i].WorkingTimeBase
-		dayDate, err := api.DateStringToTime(x.DayDate)
-		if err != nil {
-			// TODO improve
-			panic(err)
-		}
-		x.DayDate = api.TimeToISODateString(dayDate)
-
-		for _, b := range x.Breaks {
-			breakDate, err := api.DateStringToTime(b.BreakDate)
-			if err != nil {
-				// TODO improve
-				panic(err)
-			}
-			b.BreakDate = api.TimeToISODateString(breakDate)
-		}
-
-		days = append(days, x)
+	for _, x := range sheet.WorkingTimeDays {
+		days = append(days, x.WorkingTimeBase)
 	}
 	return days
 }
 
-//salaryTime gets a pointer to the activity with the given ID. returns error if not found
-func (w *Week) salaryTime(activityID int) (*api.SalaryTime, error) {
+//salaryTime gets a pointer to the activity with the given ID, fetching it if it is not in the week yet. returns error if not found
+func (w *Week) salaryTime(ctx context.Context, activityID int) (*api.SalaryTime, error) {
 
 	for i, x := range w.sheet.ListOfSalaryTime {
 		if x.ActivityID == activityID {
@@ -93,7 +79,7 @@ func (w *Week) salaryTime(activityID int) (*api.SalaryTime, error) {
 	}
 
 	// unable to find the activity sheet, it's a new activity for this week. Fetch and add it
-	tempSalaryActivity, err := w.client.apiClient.GetSalaryActivity(w.client.employeeID, activityID, w.start, w.end)
+	tempSalaryActivity, err := w.client.apiClient.GetSalaryActivityContext(ctx, w.client.employeeID, activityID, w.start, w.end)
 	if err != nil {
 		return nil, fmt.Errorf("unable to find salary time activity with ActivityID %d: %v", activityID, err)
 	}
@@ -104,8 +90,8 @@ func (w *Week) salaryTime(activityID int) (*api.SalaryTime, error) {
 	return salaryTime, nil
 }
 
-//projectTime gets a pointer to the activity with the given ID. returns error if not found
-func (w *Week) projectTime(activityID int) (*api.ProjectTime, error) {
+//projectTime gets a pointer to the activity with the given ID, fetching it if it is not in the week yet. returns error if not found
+func (w *Week) projectTime(ctx context.Context, activityID int) (*api.ProjectTime, error) {
 
 	for i, x := range w.sheet.ListOfProjectTime {
 		if x.ActivityID == activityID {
@@ -114,7 +100,7 @@ func (w *Week) projectTime(activityID int) (*api.ProjectTime, error) {
 	}
 
 	// project found with the given activity id was not found. Lets fetch and add it
-	tempProjectTime, err := w.client.apiClient.GetProjectActivity(w.client.employeeID, activityID, w.start, w.end)
+	tempProjectTime, err := w.client.apiClient.GetProjectActivityContext(ctx, w.client.employeeID, activityID, w.start, w.end)
 	if err != nil {
 		return nil, fmt.Errorf("unable to find project activity with ActivityID %d", activityID)
 	}
@@ -138,17 +124,21 @@ func (e ErrorSaveWorkingTimeResponse) Error() string {
 	return e.message
 }
 
-func (w *Week) saveWorkingTime() (*api.SaveWorkingTimeResponse, error) {
-
-	t := api.EmployeeWorkingTime{
-		Days:       w.workingTimeDays(),
+//workingTimePayload builds what saveWorkingTime sends
+func (w *Week) workingTimePayload(sheet *api.TimesheetData) api.EmployeeWorkingTime {
+	return api.EmployeeWorkingTime{
+		Days:       w.workingTimeDays(sheet),
 		EmployeeID: w.client.employeeID,
-		FromDate:   api.TimeToISODateString(w.start),
-		ToDate:     api.TimeToISODateString(w.end),
+		FromDate:   api.NewDate(w.start),
+		ToDate:     api.NewDate(w.end),
 	}
-	response, err := w.client.apiClient.SaveWorkingTime(t)
+}
+
+func (w *Week) saveWorkingTime(ctx context.Context, sheet *api.TimesheetData) (*api.SaveWorkingTimeResponse, error) {
+
+	response, err := w.client.apiClient.SaveWorkingTimeContext(ctx, w.workingTimePayload(sheet))
 	if err != nil {
-		return nil, fmt.Errorf("unable to save working time: %v", err)
+		return nil, err
 	}
 	if response.Saved != "" {
 		// this means it failed to save, i think
@@ -169,18 +159,22 @@ func (e ErrorSaveSalaryTimeResponse) Error() string {
 	return e.message
 }
 
-func (w *Week) saveSalaryTime() (*api.SaveSalaryTimeResponse, error) {
-
-	t := api.EmployeeSalaryTime{
+//salaryTimePayload builds what saveSalaryTime sends. It includes the working time.
+func (w *Week) salaryTimePayload(sheet *api.TimesheetData) api.EmployeeSalaryTime {
+	return api.EmployeeSalaryTime{
 		EmployeeID:  w.client.employeeID,
-		FromDate:    api.TimeToISODateString(w.start),
-		ToDate:      api.TimeToISODateString(w.end),
-		SalaryTime:  w.salaryTimeDays(),
-		WorkingTime: w.workingTimeDays(),
+		FromDate:    api.NewDate(w.start),
+		ToDate:      api.NewDate(w.end),
+		SalaryTime:  w.salaryTimeDays(sheet),
+		WorkingTime: w.workingTimeDays(sheet),
 	}
-	response, err := w.client.apiClient.SaveSalaryTime(t)
+}
+
+func (w *Week) saveSalaryTime(ctx context.Context, sheet *api.TimesheetData) (*api.SaveSalaryTimeResponse, error) {
+
+	response, err := w.client.apiClient.SaveSalaryTimeContext(ctx, w.salaryTimePayload(sheet))
 	if err != nil {
-		return nil, fmt.Errorf("unable to save salary time: %v", err)
+		return nil, err
 	}
 	if response.WasSaved != true {
 		return nil, ErrorSaveSalaryTimeResponse{SaveSalaryTimeResponse: response, message: "error saving"}
@@ -199,17 +193,21 @@ func (e ErrorSaveProjectTimeResponse) Error() string {
 	return e.message
 }
 
-func (w *Week) saveProjectTime() (*api.SaveProjectTimeResponse, error) {
-
-	t := api.EmployeeProjectTime{
+//projectTimePayload builds what saveProjectTime sends
+func (w *Week) projectTimePayload(sheet *api.TimesheetData) api.EmployeeProjectTime {
+	return api.EmployeeProjectTime{
 		EmployeeID: w.client.employeeID,
-		FromDate:   api.TimeToISODateString(w.start),
-		ToDate:     api.TimeToISODateString(w.end),
-		List:       w.projectTimeDays(),
+		FromDate:   api.NewDate(w.start),
+		ToDate:     api.NewDate(w.end),
+		List:       w.projectTimeDays(sheet),
 	}
-	response, err := w.client.apiClient.SaveProjectTime(t)
+}
+
+func (w *Week) saveProjectTime(ctx context.Context, sheet *api.TimesheetData) (*api.SaveProjectTimeResponse, error) {
+
+	response, err := w.client.apiClient.SaveProjectTimeContext(ctx, w.projectTimePayload(sheet))
 	if err != nil {
-		return nil, fmt.Errorf("unable to save project time: %v", err)
+		return nil, err
 	}
 	if response.WasSaved != true {
 		return nil, ErrorSaveProjectTimeResponse{SaveProjectTimeResponse: response, message: "error saving"}
@@ -217,60 +215,135 @@ func (w *Week) saveProjectTime() (*api.SaveProjectTimeResponse, error) {
 	return response, nil
 }
 
-//Save saves the week
-func (w *Week) Save() error {
-	if !w.changed {
-		return fmt.Errorf("week has not changed (according to 'changed' flag)")
+//Save saves the sections of the week that have changed. The result tells how each section went and carries
+//the limit errors, warnings and validation messages returned by Qbis, also when saving fails.
+//Use WithLogger to see the raw responses.
+//
+//The changes are first checked with the rules of the client, see DefaultRules and WithRules.
+//If any rule is broken Save returns ValidationErrors without saving anything.
+//
+//Before saving, the week is fetched again and compared to how it was when it was loaded. If it was changed in Qbis,
+//in cells that saving would overwrite, Save returns a *ConflictError without saving anything.
+//
+//If a section fails after others were saved, the saved sections are rolled back by posting the values
+//they had when the week was loaded, and Save returns a *PartialSaveError. Use WithoutRollback to keep them.
+func (w *Week) Save(opts ...SaveOption) (*SaveResult, error) {
+	return w.SaveContext(context.Background(), opts...)
+}
+
+//SaveContext is like Save but with a context
+func (w *Week) SaveContext(ctx context.Context, opts ...SaveOption) (*SaveResult, error) {
+	o := newSaveOptions(opts)
+
+	sections := w.ChangedSections()
+	if len(sections) == 0 {
+		return nil, fmt.Errorf("week has no changes to save")
 	}
 
-	salRes, err := w.saveSalaryTime()
-	if err != nil {
-		salErr, ok := err.(ErrorSaveSalaryTimeResponse)
-		if ok {
-			return fmt.Errorf("error saving working time: %s (wasSaved: %b)", salErr.message, salErr.WasSaved)
+	if !o.noValidation {
+		errs := w.Validate()
+		if len(errs) > 0 {
+			return nil, errs
 		}
-		return fmt.Errorf("error saving working time: %v", err)
 	}
 
-	workRes, err := w.saveWorkingTime()
-	if err != nil {
-		workErr, ok := err.(ErrorSaveWorkingTimeResponse)
-		if ok {
-			return fmt.Errorf("error saving working time: %s (Saved: %s)", workErr.message, workErr.Saved)
+	if !o.force {
+		err := w.checkConflicts(ctx, sections)
+		if err != nil {
+			return nil, err
 		}
-		return fmt.Errorf("error saving working time: %v", err)
 	}
+	result := newSaveResult()
 
-	projRes, err := w.saveProjectTime()
-	if err != nil {
-		projErr, ok := err.(ErrorSaveProjectTimeResponse)
-		if ok {
-			return fmt.Errorf("error saving working time: %s (wasSaved: %b)", projErr.message, projErr.WasSaved)
+	committed := make([]Section, 0)
+	for _, section := range sections {
+		err := w.saveSection(ctx, section, w.sheet, result)
+		if err != nil {
+			if len(committed) == 0 {
+				return result, err
+			}
+			return result, w.undo(ctx, section, err, committed, result, o)
 		}
-		return fmt.Errorf("error saving working time: %v", err)
+		committed = append(committed, section)
+		delete(w.changed, section)
+	}
+
+	err := w.UpdateContext(ctx)
+	if err != nil {
+		return result, fmt.Errorf("week was saved but could not be refreshed: %w", err)
 	}
+	result.Timesheet = w.sheet
+	return result, nil
+}
 
-	// data was saved
-	fmt.Println("Debug printing warning messages etc.")
-	fmt.Printf("Working time response: %+v\n", workRes)
-	fmt.Printf("Salary time response: %+v\n", salRes)
-	fmt.Printf("Project time response: %+v\n", projRes)
+//saveSection saves one section of the week and records the outcome in the result
+func (w *Week) saveSection(ctx context.Context, section Section, sheet *api.TimesheetData, result *SaveResult) error {
+	var err error
+
+	switch section {
+	case SectionSalaryTime:
+		var res *api.SaveSalaryTimeResponse
+		res, err = w.saveSalaryTime(ctx, sheet)
+		if salErr, ok := err.(ErrorSaveSalaryTimeResponse); ok {
+			res = salErr.SaveSalaryTimeResponse
+			err = fmt.Errorf("%w (wasSaved: %t)", salErr, salErr.WasSaved)
+		}
+		if res != nil {
+			w.client.logger.Printf("salary time response: %+v", res)
+			result.addSalaryTime(res)
+		}
+	case SectionWorkingTime:
+		var res *api.SaveWorkingTimeResponse
+		res, err = w.saveWorkingTime(ctx, sheet)
+		if workErr, ok := err.(ErrorSaveWorkingTimeResponse); ok {
+			res = workErr.SaveWorkingTimeResponse
+			err = fmt.Errorf("%w (Saved: %s)", workErr, workErr.Saved)
+		}
+		if res != nil {
+			w.client.logger.Printf("working time response: %+v", res)
+			result.addWorkingTime(res)
+		}
+	case SectionProjectTime:
+		var res *api.SaveProjectTimeResponse
+		res, err = w.saveProjectTime(ctx, sheet)
+		if projErr, ok := err.(ErrorSaveProjectTimeResponse); ok {
+			res = projErr.SaveProjectTimeResponse
+			err = fmt.Errorf("%w (wasSaved: %t)", projErr, projErr.WasSaved)
+		}
+		if res != nil {
+			w.client.logger.Printf("project time response: %+v", res)
+			result.addProjectTime(res)
+		}
+	default:
+		err = fmt.Errorf("unknown section %d", section)
+	}
 
-	err = w.Update()
+	result.setSection(section, err)
 	if err != nil {
-		return err
+		return fmt.Errorf("error saving %v: %w", section, err)
 	}
-	w.changed = false
 	return nil
 }
 
-//Update refreshes timesheet data
+//Update refreshes timesheet data, discarding changes that have not been saved
 func (w *Week) Update() error {
-	sheet, err := w.client.apiClient.GetTimesheet(w.client.employeeID, w.start, w.end)
+	return w.UpdateContext(context.Background())
+}
+
+//UpdateContext is like Update but with a context
+func (w *Week) UpdateContext(ctx context.Context) error {
+	sheet, err := w.client.apiClient.GetTimesheetContext(ctx, w.client.employeeID, w.start, w.end)
+	if err != nil {
+		return err
+	}
+	original, err := cloneSheet(sheet)
 	if err != nil {
 		return err
 	}
 	w.sheet = sheet
+	w.original = original
+	// anything not saved is gone with the old sheet
+	w.changed = nil
 
 	return nil
 }
@@ -314,19 +387,13 @@ func (w Week) OvertimeMinutes() int {
 
 // DAY
 
-//Day gets the day of the timestamp
+//Day gets the day of the timestamp, in the time zone of the client
 func (w *Week) Day(dayOf time.Time) (*Day, error) {
-	year, month, day := dayOf.Date()
 	var foundDaySettings *api.DaySetting
 	foundDaySettings = nil
 	foundDayIndex := -1
 	for i, ds := range w.sheet.DaySettings {
-		t, err := api.DateStringToTime(ds.DayDate)
-		if err != nil {
-			return nil, fmt.Errorf("unable to parse DayDate string '%s': %v", ds.DayDate, err)
-		}
-		iyear, imonth, iday := t.Date()
-		if iyear == year && imonth == month && day == iday {
+		if sameDate(ds.DayDate.Time, dayOf, w.client.location) {
 			foundDaySettings = &ds
 			foundDayIndex = i
 			break
@@ -341,7 +408,7 @@ func (w *Week) Day(dayOf time.Time) (*Day, error) {
 	pDay.indexInWeek = foundDayIndex
 	pDay.week = w
 
-	qbisDate, err := api.GetDateForDateTime(dayOf)
+	qbisDate, err := api.GetDateForDateTimeIn(dayOf, w.client.location)
 	if err != nil {
 		return nil, err
 	}
@@ -366,7 +433,7 @@ func getNextWeekday(startDate time.Time, endDate time.Time, dayInWeek time.Weekd
 	for dayDate.Weekday() != dayInWeek {
 		dayDate = dayDate.AddDate(0, 0, 1)
 		if dayDate.After(endDate) {
-			return time.Unix(0, 0), fmt.Errorf("unable to find weekday %d in week")
+			return time.Unix(0, 0), fmt.Errorf("unable to find weekday %d in week", dayInWeek)
 		}
 	}
 
@@ -375,8 +442,9 @@ func getNextWeekday(startDate time.Time, endDate time.Time, dayInWeek time.Weekd
 
 // SALARY TIME
 
-//SalaryTimeActivities returns a list of all available SalaryTime activities
-func (w *Week) SalaryTimeActivities() []SalaryActivity {
+//SalaryTimeActivities returns a list of all available SalaryTime activities.
+//A *DefaultSalaryActivityError is returned if Qbis marks more than one of them as the default.
+func (w *Week) SalaryTimeActivities() ([]SalaryActivity, error) {
 	return salaryTimeActivities(w)
 }
 
@@ -384,5 +452,10 @@ func (w *Week) SalaryTimeActivities() []SalaryActivity {
 
 //ProjectTimeActivities returns a list of all project activities available to the employee
 func (w *Week) ProjectTimeActivities() (*ProjectActivityList, error) {
-	return newProjectActivityList(w)
+	return w.ProjectTimeActivitiesContext(context.Background())
+}
+
+//ProjectTimeActivitiesContext is like ProjectTimeActivities but with a context, which is also used when the list fetches the activities of its projects
+func (w *Week) ProjectTimeActivitiesContext(ctx context.Context) (*ProjectActivityList, error) {
+	return newProjectActivityList(ctx, w)
 }